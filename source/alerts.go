@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strings"
+)
+
+// defaultAlertSound is played for visible alerts when a device hasn't chosen a sound
+const defaultAlertSound = "default"
+
+// Alert is the user-visible text of a push notification
+type Alert struct {
+	Title string
+	Body  string
+}
+
+// Apply adds the alert to a push for a device that wants visible alerts. Pushes to
+// other devices stay silent background pushes.
+func (a Alert) Apply(req *PushRequest, device DeviceRegistration) {
+	if !device.Alerts {
+		return
+	}
+	req.Title = a.Title
+	req.Body = a.Body
+	req.Sound = device.Sound
+	if req.Sound == "" {
+		req.Sound = defaultAlertSound
+	}
+}
+
+// alertCategory is the notification category for an event type, which the app registers
+// actions for: the event type in upper case, e.g. STATUS_CHANGE
+func alertCategory(eventType string) string {
+	return strings.ToUpper(eventType)
+}
+
+// AlertBuilder builds the alert text for bus messages from notification templates, in each
+// device's locale
+type AlertBuilder struct {
+	parkManager *ParkManager
+	templates   *NotificationTemplates
+}
+
+// NewAlertBuilder creates an AlertBuilder that looks park names up in parkManager and renders templates
+func NewAlertBuilder(parkManager *ParkManager, templates *NotificationTemplates) *AlertBuilder {
+	return &AlertBuilder{parkManager: parkManager, templates: templates}
+}
+
+// StatusChange describes an entity changing status, e.g. "Space Mountain is back up — 35 min wait"
+func (b *AlertBuilder) StatusChange(msg StatusChangeMessage, locale string) Alert {
+	data := b.data(msg.EntityID, msg.EntityName, msg.ParkID)
+	data.OldStatus = string(msg.OldStatus)
+	data.NewStatus = string(msg.NewStatus)
+	data.OldWaitTime = msg.OldWaitTime
+	data.NewWaitTime = msg.NewWaitTime
+	return b.templates.Render(locale, EventStatusChange, string(msg.NewStatus), data)
+}
+
+// WaitTime describes a wait time dropping to a device's alert threshold
+func (b *AlertBuilder) WaitTime(msg WaitTimeMessage, locale string) Alert {
+	data := b.data(msg.EntityID, msg.EntityName, msg.ParkID)
+	data.OldStatus = string(msg.Status)
+	data.NewStatus = string(msg.Status)
+	data.OldWaitTime = msg.OldWaitTime
+	data.NewWaitTime = msg.NewWaitTime
+	return b.templates.Render(locale, EventWaitTime, "", data)
+}
+
+// QueueOpened describes a return time or virtual queue becoming available
+func (b *AlertBuilder) QueueOpened(msg QueueChangeMessage, locale string) Alert {
+	data := b.queueData(msg)
+	return b.templates.Render(locale, EventQueueOpened, msg.QueueType, data)
+}
+
+// BoardingGroup describes the boarding groups a device is waiting for being called,
+// or the virtual queue opening for alerts without a group range
+func (b *AlertBuilder) BoardingGroup(msg QueueChangeMessage, alert BoardingGroupAlert, locale string) Alert {
+	data := b.queueData(msg)
+	if alert.GroupStart == 0 {
+		return b.templates.Render(locale, EventBoardingGroup, QueueAvailable, data)
+	}
+	return b.templates.Render(locale, EventBoardingGroup, "", data)
+}
+
+// queueData returns the template data for a queue change
+func (b *AlertBuilder) queueData(msg QueueChangeMessage) NotificationData {
+	data := b.data(msg.EntityID, msg.EntityName, msg.ParkID)
+	data.OldStatus = msg.OldQueue.Availability()
+	data.NewStatus = msg.NewQueue.Availability()
+	data.QueueType = msg.QueueType
+	if msg.NewQueue.CurrentGroupStart != nil && msg.NewQueue.CurrentGroupEnd != nil {
+		data.GroupStart = *msg.NewQueue.CurrentGroupStart
+		data.GroupEnd = *msg.NewQueue.CurrentGroupEnd
+	}
+	return data
+}
+
+// data returns the template data naming an entity and its park
+func (b *AlertBuilder) data(entityID string, name string, parkID string) NotificationData {
+	data := NotificationData{
+		Entity:   name,
+		EntityID: entityID,
+		ParkID:   parkID,
+	}
+	if data.Entity == "" {
+		data.Entity = entityID
+	}
+	if park, ok := b.parkManager.ResolvePark(parkID); ok {
+		data.Park = park.Name
+	}
+	return data
+}
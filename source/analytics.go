@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// statsWindows maps the supported ?window= values to their duration
+var statsWindows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// DowntimeStats summarizes how often an entity (or every entity in a park) went DOWN in a window.
+// The entity_events rows these are built from are written alongside every StatusChangeMessage,
+// so the stats survive restarts. Durations are in seconds; MTBF and MTTR are 0 when undefined.
+type DowntimeStats struct {
+	DownEvents           int   `json:"downEvents"`
+	TotalDowntimeSeconds int64 `json:"totalDowntimeSeconds"`
+	MTBFSeconds          int64 `json:"mtbfSeconds"`
+	MTTRSeconds          int64 `json:"mttrSeconds"`
+
+	// Totals behind the means, kept so park stats can be aggregated from entity stats
+	uptime     time.Duration
+	downtime   time.Duration
+	recoveries int
+	recovered  time.Duration
+}
+
+// parseStatsWindow returns the time range for a window name, ending now
+func parseStatsWindow(window string) (time.Time, time.Time, error) {
+	if window == "" {
+		window = "day"
+	}
+	duration, ok := statsWindows[window]
+	if !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("window must be one of day, week or month")
+	}
+	to := time.Now().UTC()
+	return to.Add(-duration), to, nil
+}
+
+// computeDowntimeStats walks an entity's status changes (oldest first) across [from, to].
+// initialStatus is the status at the start of the window when no change says otherwise.
+func computeDowntimeStats(events []EntityEvent, from time.Time, to time.Time, initialStatus EntityStatus) DowntimeStats {
+	var stats DowntimeStats
+
+	status := initialStatus
+	if len(events) > 0 {
+		status = EntityStatus(events[0].OldStatus)
+	}
+	since := from
+
+	// Add the time spent in the current status up to at, and advance
+	account := func(at time.Time) time.Duration {
+		elapsed := at.Sub(since)
+		switch status {
+		case StatusDown:
+			stats.downtime += elapsed
+		case StatusOperating:
+			stats.uptime += elapsed
+		}
+		since = at
+		return elapsed
+	}
+
+	var downFor time.Duration
+	for _, event := range events {
+		elapsed := account(event.Timestamp)
+		newStatus := EntityStatus(event.NewStatus)
+
+		if status == StatusDown {
+			downFor += elapsed
+			if newStatus != StatusDown {
+				stats.recoveries++
+				stats.recovered += downFor
+				downFor = 0
+			}
+		}
+		if newStatus == StatusDown && status != StatusDown {
+			stats.DownEvents++
+		}
+		status = newStatus
+	}
+	account(to)
+
+	stats.finalize()
+	return stats
+}
+
+// add folds another entity's stats into a park total
+func (s *DowntimeStats) add(other DowntimeStats) {
+	s.DownEvents += other.DownEvents
+	s.uptime += other.uptime
+	s.downtime += other.downtime
+	s.recoveries += other.recoveries
+	s.recovered += other.recovered
+	s.finalize()
+}
+
+// finalize derives the exported fields from the accumulated totals
+func (s *DowntimeStats) finalize() {
+	s.TotalDowntimeSeconds = int64(s.downtime.Seconds())
+	s.MTBFSeconds = 0
+	if s.DownEvents > 0 {
+		s.MTBFSeconds = int64(s.uptime.Seconds()) / int64(s.DownEvents)
+	}
+	s.MTTRSeconds = 0
+	if s.recoveries > 0 {
+		s.MTTRSeconds = int64(s.recovered.Seconds()) / int64(s.recoveries)
+	}
+}
+
+// GetEntityDowntimeStats computes downtime stats for a single entity
+func GetEntityDowntimeStats(entityManager *EntityManager, entityID string, from time.Time, to time.Time) (DowntimeStats, error) {
+	events, err := db.GetStatusChangeEvents(entityID, "", from, to)
+	if err != nil {
+		return DowntimeStats{}, err
+	}
+
+	entity, _ := entityManager.GetEntity(entityID)
+	return computeDowntimeStats(events, from, to, entity.Status), nil
+}
+
+// GetParkDowntimeStats computes downtime stats for every entity in a park, plus the park total.
+// The ID may be a configured park (destination), which includes all of its child parks.
+func GetParkDowntimeStats(entityManager *EntityManager, parkManager *ParkManager, parkID string, from time.Time, to time.Time) (DowntimeStats, map[string]DowntimeStats, error) {
+	parkIDs := append([]string{parkID}, parkManager.GetChildParkIDs(parkID)...)
+
+	// Group events by entity, keeping them in time order. Each entity is in a single park,
+	// so its events all come from one query.
+	byEntity := make(map[string][]EntityEvent)
+	for _, id := range parkIDs {
+		events, err := db.GetStatusChangeEvents("", id, from, to)
+		if err != nil {
+			return DowntimeStats{}, nil, err
+		}
+		for _, event := range events {
+			byEntity[event.EntityID] = append(byEntity[event.EntityID], event)
+		}
+	}
+
+	// Entities without changes in the window still count towards uptime/downtime
+	for _, entity := range entityManager.GetEntitiesByPark(parkIDs...) {
+		if _, ok := byEntity[entity.EntityID]; !ok {
+			byEntity[entity.EntityID] = nil
+		}
+	}
+
+	var total DowntimeStats
+	perEntity := make(map[string]DowntimeStats, len(byEntity))
+	for entityID, entityEvents := range byEntity {
+		entity, _ := entityManager.GetEntity(entityID)
+		stats := computeDowntimeStats(entityEvents, from, to, entity.Status)
+		perEntity[entityID] = stats
+		total.add(stats)
+	}
+
+	return total, perEntity, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+var statsFrom = time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+
+// statusEvent is a status change the given number of hours into the stats window
+func statusEvent(hours float64, oldStatus EntityStatus, newStatus EntityStatus) EntityEvent {
+	return EntityEvent{
+		EntityID:  "ride",
+		OldStatus: string(oldStatus),
+		NewStatus: string(newStatus),
+		Timestamp: statsFrom.Add(time.Duration(hours * float64(time.Hour))),
+	}
+}
+
+func TestComputeDowntimeStats(t *testing.T) {
+	to := statsFrom.Add(10 * time.Hour)
+	hour := int64(time.Hour.Seconds())
+
+	tests := []struct {
+		name          string
+		events        []EntityEvent
+		initialStatus EntityStatus
+		want          DowntimeStats
+	}{
+		{
+			name:          "operating all window",
+			initialStatus: StatusOperating,
+			want:          DowntimeStats{},
+		},
+		{
+			name:          "down all window",
+			initialStatus: StatusDown,
+			want:          DowntimeStats{TotalDowntimeSeconds: 10 * hour},
+		},
+		{
+			// The first change's old status, not the current one, is the start-of-window status
+			name: "one outage",
+			events: []EntityEvent{
+				statusEvent(2, StatusOperating, StatusDown),
+				statusEvent(3, StatusDown, StatusOperating),
+			},
+			initialStatus: StatusDown,
+			want:          DowntimeStats{DownEvents: 1, TotalDowntimeSeconds: hour, MTBFSeconds: 9 * hour, MTTRSeconds: hour},
+		},
+		{
+			// Going down before the window isn't a down event, but recovering within it is a repair
+			name: "down at the start of the window",
+			events: []EntityEvent{
+				statusEvent(1, StatusDown, StatusOperating),
+				statusEvent(5, StatusOperating, StatusDown),
+				statusEvent(7, StatusDown, StatusOperating),
+			},
+			initialStatus: StatusOperating,
+			want:          DowntimeStats{DownEvents: 1, TotalDowntimeSeconds: 3 * hour, MTBFSeconds: 7 * hour, MTTRSeconds: 90 * 60},
+		},
+		{
+			name: "still down at the end of the window",
+			events: []EntityEvent{
+				statusEvent(8, StatusOperating, StatusDown),
+			},
+			initialStatus: StatusDown,
+			want:          DowntimeStats{DownEvents: 1, TotalDowntimeSeconds: 2 * hour, MTBFSeconds: 8 * hour},
+		},
+		{
+			name: "closed time is neither uptime nor downtime",
+			events: []EntityEvent{
+				statusEvent(4, StatusOperating, StatusClosed),
+				statusEvent(6, StatusClosed, StatusOperating),
+				statusEvent(8, StatusOperating, StatusDown),
+				statusEvent(9, StatusDown, StatusOperating),
+			},
+			initialStatus: StatusOperating,
+			want:          DowntimeStats{DownEvents: 1, TotalDowntimeSeconds: hour, MTBFSeconds: 7 * hour, MTTRSeconds: hour},
+		},
+		{
+			name: "closing while down ends the repair",
+			events: []EntityEvent{
+				statusEvent(2, StatusOperating, StatusDown),
+				statusEvent(4, StatusDown, StatusClosed),
+			},
+			initialStatus: StatusClosed,
+			want:          DowntimeStats{DownEvents: 1, TotalDowntimeSeconds: 2 * hour, MTBFSeconds: 2 * hour, MTTRSeconds: 2 * hour},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeDowntimeStats(tt.events, statsFrom, to, tt.initialStatus)
+			if got.DownEvents != tt.want.DownEvents || got.TotalDowntimeSeconds != tt.want.TotalDowntimeSeconds ||
+				got.MTBFSeconds != tt.want.MTBFSeconds || got.MTTRSeconds != tt.want.MTTRSeconds {
+				t.Errorf("got %d down events, %ds down, MTBF %ds, MTTR %ds; want %d, %ds, %ds, %ds",
+					got.DownEvents, got.TotalDowntimeSeconds, got.MTBFSeconds, got.MTTRSeconds,
+					tt.want.DownEvents, tt.want.TotalDowntimeSeconds, tt.want.MTBFSeconds, tt.want.MTTRSeconds)
+			}
+		})
+	}
+}
+
+func TestDowntimeStatsAdd(t *testing.T) {
+	to := statsFrom.Add(10 * time.Hour)
+
+	// Park means come from the entities' totals, not from averaging their means
+	var total DowntimeStats
+	total.add(computeDowntimeStats([]EntityEvent{
+		statusEvent(2, StatusOperating, StatusDown),
+		statusEvent(3, StatusDown, StatusOperating),
+	}, statsFrom, to, StatusOperating))
+	total.add(computeDowntimeStats([]EntityEvent{
+		statusEvent(1, StatusDown, StatusOperating),
+		statusEvent(5, StatusOperating, StatusDown),
+		statusEvent(7, StatusDown, StatusOperating),
+	}, statsFrom, to, StatusOperating))
+
+	hour := int64(time.Hour.Seconds())
+	if total.DownEvents != 2 || total.TotalDowntimeSeconds != 4*hour || total.MTBFSeconds != 8*hour || total.MTTRSeconds != 80*60 {
+		t.Errorf("park total = %+v, want 2 down events, 4h down, MTBF 8h and MTTR 80m", total)
+	}
+}
@@ -335,139 +335,238 @@ func SendPushNotification(req NotificationRequest) error {
 	return nil
 }
 
+// PushRetryConfig controls how workers retry pushes that fail with a transient APNS error
+type PushRetryConfig struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
 // StartAPNSWorkers starts a pool of workers to send push notifications.
-func StartAPNSWorkers(numWorkers int) {
+func StartAPNSWorkers(numWorkers int, retry PushRetryConfig) {
 	log.Printf("Starting %d APNS worker(s)...", numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		go apnsSender(i + 1)
+		go apnsSender(i+1, retry)
 	}
 }
 
-// apnsSender is a single worker that consumes from the PushQueue.
-func apnsSender(id int) {
+// apnsSender is a single worker that consumes from the push queue until it is closed.
+func apnsSender(id int, retry PushRetryConfig) {
 	log.Printf("APNS Sender Worker %d started", id)
 	bundleID := os.Getenv("APNS_BUNDLE_ID")
 
-	for req := range PushQueue {
-		log.Printf("[Worker %d] Sending push to %s (Environment: %s)", id, req.DeviceToken, req.Environment)
+	for {
+		req, ok := pushQueue.Dequeue()
+		if !ok {
+			log.Printf("APNS Sender Worker %d stopped", id)
+			return
+		}
+		sendWithRetry(id, bundleID, req, retry)
+	}
+}
 
-		// Create the payload
-		payload := payload.NewPayload().
-			ContentAvailable().
-			Badge(1).
-			Custom("entityId", req.EntityID).
-			Custom("parkId", req.ParkID).
-			Custom("oldStatus", req.OldStatus).
-			Custom("newStatus", req.NewStatus).
-			Custom("oldWaitTime", req.OldWaitTime).
-			Custom("newWaitTime", req.NewWaitTime)
+// sendWithRetry makes one attempt to send a push. A push that fails for a transient reason is
+// put back on the queue once its backoff delay has passed, so the worker moves on to other pushes
+// meanwhile; its outbox lease outlasts the delay. Pushes still failing after the last attempt are
+// stored as dead letters so they can be re-driven.
+func sendWithRetry(id int, bundleID string, req PushRequest, retry PushRetryConfig) {
+	// Only the holder of the push's latest outbox lease may send it
+	if !pushOutbox.Renew(&req) {
+		log.Printf("[Worker %d] Skipping push %d to %s, it was re-queued or already sent", id, req.OutboxID, req.DeviceToken)
+		return
+	}
 
-		// Log the payload structure for debugging
-		log.Printf("[Worker %d] APNS Payload Structure: {\"aps\":{\"content-available\":1,\"badge\":1},\"entityId\":\"%s\",\"parkId\":\"%s\",\"oldStatus\":\"%s\",\"newStatus\":\"%s\",\"oldWaitTime\":%d,\"newWaitTime\":%d}", 
-			id, req.EntityID, req.ParkID, req.OldStatus, req.NewStatus, req.OldWaitTime, req.NewWaitTime)
+	retryable, reason := sendPush(id, bundleID, req)
+	if !retryable {
+		pushOutbox.Ack(req)
+		return
+	}
 
-		notification := &apns2.Notification{
-			DeviceToken: req.DeviceToken,
-			Topic:       bundleID,
-			Payload:     payload,
+	req.Attempts++
+	if req.Attempts >= retry.MaxAttempts {
+		log.Printf("[Worker %d] Giving up on push to %s after %d attempts: %s", id, req.DeviceToken, req.Attempts, reason)
+		deadLetter := DeadLetter{
+			Request:   req,
+			Attempts:  req.Attempts,
+			LastError: reason,
+		}
+		// Re-driving a dead letter adds it to the outbox again as a new push
+		deadLetter.Request.OutboxID = 0
+		deadLetter.Request.LeaseID = 0
+		deadLetter.Request.Attempts = 0
+		if err := db.StoreDeadLetter(deadLetter); err != nil {
+			log.Printf("[Worker %d] Failed to store dead letter: %v", id, err)
 		}
+		pushOutbox.Ack(req)
+		return
+	}
 
-		// Get the appropriate APNS client based on the environment
-		client := getAPNSClient(req.Environment)
-		
-		res, err := client.Push(notification)
+	delay := retry.delay(req.Attempts)
+	log.Printf("[Worker %d] Retrying push to %s in %v (attempt %d of %d failed: %s)", id, req.DeviceToken, delay.Round(time.Millisecond), req.Attempts, retry.MaxAttempts, reason)
+	time.AfterFunc(delay, func() {
+		pushQueue.Enqueue(req)
+	})
+}
+
+// delay returns the backoff delay before retrying a push that has failed attempts times
+func (r PushRetryConfig) delay(attempts int) time.Duration {
+	backoff := Backoff{Initial: r.InitialDelay, Max: r.MaxDelay, attempt: attempts - 1}
+	return backoff.Next()
+}
+
+// isRetryableResponse reports whether APNS rejected a push for a transient reason:
+// rate limiting (429) or a server-side error (500, 503)
+func isRetryableResponse(res *apns2.Response) bool {
+	return res.StatusCode == 429 || res.StatusCode >= 500
+}
+
+// sendPush makes a single attempt to send a push and records it in apns_messages. It returns
+// whether the attempt failed for a reason worth retrying, and the failure reason.
+func sendPush(id int, bundleID string, req PushRequest) (retryable bool, reason string) {
+	log.Printf("[Worker %d] Sending push to %s (Environment: %s)", id, req.DeviceToken, req.Environment)
+
+	// Create the payload
+	payload := payload.NewPayload().
+		Badge(1).
+		Custom("eventType", req.EventType).
+		Custom("entityId", req.EntityID).
+		Custom("parkId", req.ParkID).
+		Custom("oldStatus", req.OldStatus).
+		Custom("newStatus", req.NewStatus).
+		Custom("oldWaitTime", req.OldWaitTime).
+		Custom("newWaitTime", req.NewWaitTime)
+	if req.QueueType != "" {
+		payload.Custom("queueType", req.QueueType)
+	}
+
+	// Visible alerts are grouped per park and carry a category for the app's actions;
+	// everything else is a silent background push for the app to refresh its data
+	if req.Body != "" {
+		payload.AlertTitle(req.Title).
+			AlertBody(req.Body).
+			Sound(req.Sound).
+			ThreadID(req.ParkID).
+			Category(alertCategory(req.EventType))
+	} else {
+		payload.ContentAvailable()
+	}
+
+	// Log the payload for debugging
+	if payloadJSON, err := payload.MarshalJSON(); err == nil {
+		log.Printf("[Worker %d] APNS Payload: %s", id, payloadJSON)
+	}
+
+	notification := &apns2.Notification{
+		DeviceToken: req.DeviceToken,
+		Topic:       bundleID,
+		Payload:     payload,
+	}
+	if req.Body != "" {
+		notification.PushType = apns2.PushTypeAlert
+	} else {
+		// APNS requires background pushes to be sent at low priority
+		notification.PushType = apns2.PushTypeBackground
+		notification.Priority = apns2.PriorityLow
+	}
+
+	// Get the appropriate APNS client based on the environment
+	client := getAPNSClient(req.Environment)
+	
+	res, err := client.Push(notification)
+	
+	// Create APNS message tracking record
+	apnsMessage := APNSMessage{
+		DeviceToken: req.DeviceToken,
+		Timestamp:   time.Now().UTC(),
+		EntityID:    req.EntityID,
+		ParkID:      req.ParkID,
+		OldStatus:   req.OldStatus,
+		NewStatus:   req.NewStatus,
+		OldWaitTime: req.OldWaitTime,
+		NewWaitTime: req.NewWaitTime,
+	}
+
+	if err != nil {
+		log.Printf("[Worker %d] Push error for token %s: %v", id, req.DeviceToken, err)
+		apnsMessage.Success = false
+		apnsMessage.ErrorReason = err.Error()
 		
-		// Create APNS message tracking record
-		apnsMessage := APNSMessage{
-			DeviceToken: req.DeviceToken,
-			Timestamp:   time.Now().UTC(),
-			EntityID:    req.EntityID,
-			ParkID:      req.ParkID,
-			OldStatus:   req.OldStatus,
-			NewStatus:   req.NewStatus,
-			OldWaitTime: req.OldWaitTime,
-			NewWaitTime: req.NewWaitTime,
+		// Store failed message in database
+		if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
+			log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
 		}
+		// Network errors are usually transient
+		return true, err.Error()
+	}
 
-		if err != nil {
-			log.Printf("[Worker %d] Push error for token %s: %v", id, req.DeviceToken, err)
-			apnsMessage.Success = false
-			apnsMessage.ErrorReason = err.Error()
-			
-			// Store failed message in database
-			if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
-				log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
-			}
-			continue
+	if res.Sent() {
+		log.Printf("[Worker %d] Push sent successfully to %s", id, req.DeviceToken)
+		apnsMessage.Success = true
+		
+		// Store successful message in database
+		if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
+			log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
 		}
-
-		if res.Sent() {
-			log.Printf("[Worker %d] Push sent successfully to %s", id, req.DeviceToken)
-			apnsMessage.Success = true
-			
-			// Store successful message in database
-			if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
-				log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
-			}
-		} else {
-			// Enhanced logging with detailed APNS response information
-			log.Printf("[Worker %d] Push failed for token %s", id, req.DeviceToken)
-			log.Printf("[Worker %d] APNS Response Details:", id)
-			log.Printf("[Worker %d]   - Status Code: %d", id, res.StatusCode)
-			log.Printf("[Worker %d]   - Reason: %s", id, res.Reason)
-			log.Printf("[Worker %d]   - ApnsID: %s", id, res.ApnsID)
-			log.Printf("[Worker %d]   - Sent: %t", id, res.Sent())
-			
-			// Log specific error details based on the reason
-			switch res.Reason {
-			case apns2.ReasonBadDeviceToken:
-				log.Printf("[Worker %d]   - Error Type: Bad Device Token (Token format is invalid or device is not registered)", id)
-			case apns2.ReasonUnregistered:
-				log.Printf("[Worker %d]   - Error Type: Unregistered (Device token is no longer valid for the topic)", id)
-			case apns2.ReasonBadTopic:
-				log.Printf("[Worker %d]   - Error Type: Bad Topic (Topic is invalid or not authorized)", id)
-			case apns2.ReasonTopicDisallowed:
-				log.Printf("[Worker %d]   - Error Type: Topic Disallowed (Topic is not allowed for this app)", id)
-			case apns2.ReasonBadExpirationDate:
-				log.Printf("[Worker %d]   - Error Type: Bad Expiration Date (Expiration date is invalid)", id)
-			case apns2.ReasonBadPriority:
-				log.Printf("[Worker %d]   - Error Type: Bad Priority (Priority value is invalid)", id)
-			case apns2.ReasonMissingDeviceToken:
-				log.Printf("[Worker %d]   - Error Type: Missing Device Token (Device token is missing)", id)
-			case apns2.ReasonMissingTopic:
-				log.Printf("[Worker %d]   - Error Type: Missing Topic (Topic is missing)", id)
-			case apns2.ReasonTooManyRequests:
-				log.Printf("[Worker %d]   - Error Type: Too Many Requests (Rate limit exceeded)", id)
-			case apns2.ReasonIdleTimeout:
-				log.Printf("[Worker %d]   - Error Type: Idle Timeout (Connection timed out)", id)
-			case apns2.ReasonShutdown:
-				log.Printf("[Worker %d]   - Error Type: Shutdown (Server is shutting down)", id)
-			case apns2.ReasonInternalServerError:
-				log.Printf("[Worker %d]   - Error Type: Internal Server Error (APNS server error)", id)
-			case apns2.ReasonServiceUnavailable:
-				log.Printf("[Worker %d]   - Error Type: Service Unavailable (APNS service unavailable)", id)
-			default:
-				log.Printf("[Worker %d]   - Error Type: Unknown (%s)", id, res.Reason)
-			}
-			
-			// Update tracking record for failed message
-			apnsMessage.Success = false
-			apnsMessage.ErrorReason = res.Reason
-			
-			// Store failed message in database
-			if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
-				log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
-			}
-			
-			// If the token is invalid or unregistered, remove it from our database
-			if res.Reason == apns2.ReasonBadDeviceToken || res.Reason == apns2.ReasonUnregistered {
-				log.Printf("[Worker %d] Removing invalid device token: %s (Reason: %s, Status: %d)", id, req.DeviceToken, res.Reason, res.StatusCode)
-				if delErr := db.DeleteDeviceToken(req.DeviceToken); delErr != nil {
-					log.Printf("[Worker %d] Error removing device token %s: %v", id, req.DeviceToken, delErr)
-				}
+	} else {
+		// Enhanced logging with detailed APNS response information
+		log.Printf("[Worker %d] Push failed for token %s", id, req.DeviceToken)
+		log.Printf("[Worker %d] APNS Response Details:", id)
+		log.Printf("[Worker %d]   - Status Code: %d", id, res.StatusCode)
+		log.Printf("[Worker %d]   - Reason: %s", id, res.Reason)
+		log.Printf("[Worker %d]   - ApnsID: %s", id, res.ApnsID)
+		log.Printf("[Worker %d]   - Sent: %t", id, res.Sent())
+		
+		// Log specific error details based on the reason
+		switch res.Reason {
+		case apns2.ReasonBadDeviceToken:
+			log.Printf("[Worker %d]   - Error Type: Bad Device Token (Token format is invalid or device is not registered)", id)
+		case apns2.ReasonUnregistered:
+			log.Printf("[Worker %d]   - Error Type: Unregistered (Device token is no longer valid for the topic)", id)
+		case apns2.ReasonBadTopic:
+			log.Printf("[Worker %d]   - Error Type: Bad Topic (Topic is invalid or not authorized)", id)
+		case apns2.ReasonTopicDisallowed:
+			log.Printf("[Worker %d]   - Error Type: Topic Disallowed (Topic is not allowed for this app)", id)
+		case apns2.ReasonBadExpirationDate:
+			log.Printf("[Worker %d]   - Error Type: Bad Expiration Date (Expiration date is invalid)", id)
+		case apns2.ReasonBadPriority:
+			log.Printf("[Worker %d]   - Error Type: Bad Priority (Priority value is invalid)", id)
+		case apns2.ReasonMissingDeviceToken:
+			log.Printf("[Worker %d]   - Error Type: Missing Device Token (Device token is missing)", id)
+		case apns2.ReasonMissingTopic:
+			log.Printf("[Worker %d]   - Error Type: Missing Topic (Topic is missing)", id)
+		case apns2.ReasonTooManyRequests:
+			log.Printf("[Worker %d]   - Error Type: Too Many Requests (Rate limit exceeded)", id)
+		case apns2.ReasonIdleTimeout:
+			log.Printf("[Worker %d]   - Error Type: Idle Timeout (Connection timed out)", id)
+		case apns2.ReasonShutdown:
+			log.Printf("[Worker %d]   - Error Type: Shutdown (Server is shutting down)", id)
+		case apns2.ReasonInternalServerError:
+			log.Printf("[Worker %d]   - Error Type: Internal Server Error (APNS server error)", id)
+		case apns2.ReasonServiceUnavailable:
+			log.Printf("[Worker %d]   - Error Type: Service Unavailable (APNS service unavailable)", id)
+		default:
+			log.Printf("[Worker %d]   - Error Type: Unknown (%s)", id, res.Reason)
+		}
+		
+		// Update tracking record for failed message
+		apnsMessage.Success = false
+		apnsMessage.ErrorReason = res.Reason
+		
+		// Store failed message in database
+		if storeErr := db.StoreAPNSMessage(apnsMessage); storeErr != nil {
+			log.Printf("[Worker %d] Failed to store APNS message record: %v", id, storeErr)
+		}
+		
+		// If the token is invalid or unregistered, remove it from our database
+		if res.Reason == apns2.ReasonBadDeviceToken || res.Reason == apns2.ReasonUnregistered {
+			log.Printf("[Worker %d] Removing invalid device token: %s (Reason: %s, Status: %d)", id, req.DeviceToken, res.Reason, res.StatusCode)
+			if delErr := db.DeleteDeviceToken(req.DeviceToken); delErr != nil {
+				log.Printf("[Worker %d] Error removing device token %s: %v", id, req.DeviceToken, delErr)
 			}
 		}
+		return isRetryableResponse(res), res.Reason
 	}
+	return false, ""
 }
 
 // GetRegisteredDevices returns all registered device tokens
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sideshow/apns2"
+)
+
+func TestIsRetryableResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		want       bool
+	}{
+		{"bad request", http.StatusBadRequest, false},
+		{"forbidden", http.StatusForbidden, false},
+		{"unregistered", http.StatusGone, false},
+		{"payload too large", http.StatusRequestEntityTooLarge, false},
+		{"too many requests", http.StatusTooManyRequests, true},
+		{"internal server error", http.StatusInternalServerError, true},
+		{"service unavailable", http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableResponse(&apns2.Response{StatusCode: tt.statusCode}); got != tt.want {
+				t.Errorf("isRetryableResponse(%d) = %v, want %v", tt.statusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+// useTestAPNS points the development APNS client at a server answering every push with
+// the given status code and reason
+func useTestAPNS(t *testing.T, statusCode int, reason string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+		if reason != "" {
+			w.Write([]byte(`{"reason":"` + reason + `"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	previous := apnsDevClient
+	apnsDevClient = &apns2.Client{Host: server.URL, HTTPClient: server.Client()}
+	t.Cleanup(func() { apnsDevClient = previous })
+}
+
+func TestSendWithRetry(t *testing.T) {
+	retry := PushRetryConfig{MaxAttempts: 3, InitialDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		reason      string
+		attempts    int // failed attempts before this one
+		requeued    bool
+		deadLetters int
+		outbox      int
+	}{
+		{"sent", http.StatusOK, "", 0, false, 0, 0},
+		{"permanent failure", http.StatusBadRequest, apns2.ReasonBadTopic, 0, false, 0, 0},
+		{"transient failure is retried", http.StatusServiceUnavailable, apns2.ReasonServiceUnavailable, 1, true, 0, 1},
+		{"last attempt is dead-lettered", http.StatusServiceUnavailable, apns2.ReasonServiceUnavailable, 2, false, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newOutboxTest(t, 10)
+			previous := pushOutbox
+			pushOutbox = outbox
+			t.Cleanup(func() { pushOutbox = previous })
+			useTestAPNS(t, tt.statusCode, tt.reason)
+
+			req := storeOutboxPush(t, "device", time.Now().Add(time.Minute))
+			req.Environment = "development"
+			req.Attempts = tt.attempts
+
+			done := make(chan struct{})
+			go func() {
+				sendWithRetry(1, "bundle", req, retry)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("sendWithRetry blocked")
+			}
+
+			if tt.requeued {
+				retried := dequeue(t)
+				if retried.OutboxID != req.OutboxID || retried.LeaseID != 2 || retried.Attempts != tt.attempts+1 {
+					t.Errorf("re-queued %+v, want push %d under lease 2 after %d attempts", retried, req.OutboxID, tt.attempts+1)
+				}
+			} else {
+				time.Sleep(5 * retry.MaxDelay)
+				if enqueued := pushQueue.GetStats()["enqueued"]; enqueued != 0 {
+					t.Errorf("%v pushes re-queued, want none", enqueued)
+				}
+			}
+
+			deadLetters, err := db.GetDeadLetters(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deadLetters) != tt.deadLetters {
+				t.Fatalf("stored %d dead letters, want %d", len(deadLetters), tt.deadLetters)
+			}
+			for _, deadLetter := range deadLetters {
+				if deadLetter.Attempts != retry.MaxAttempts || deadLetter.LastError != tt.reason {
+					t.Errorf("dead letter after %d attempts with %q, want %d attempts with %q", deadLetter.Attempts, deadLetter.LastError, retry.MaxAttempts, tt.reason)
+				}
+				if deadLetter.Request.OutboxID != 0 || deadLetter.Request.Attempts != 0 {
+					t.Errorf("dead letter request %+v should be re-driven as a new push", deadLetter.Request)
+				}
+			}
+			if count := countOutbox(t); count != tt.outbox {
+				t.Errorf("outbox holds %d pushes, want %d", count, tt.outbox)
+			}
+		})
+	}
+}
+
+func TestSendPushType(t *testing.T) {
+	useTestDB(t)
+
+	tests := []struct {
+		name     string
+		body     string
+		pushType string
+		priority string
+	}{
+		{"alert", "Space Mountain is back up", "alert", ""},
+		{"silent", "", "background", "5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type push struct {
+				header  http.Header
+				payload []byte
+			}
+			pushes := make(chan push, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload, _ := io.ReadAll(r.Body)
+				pushes <- push{r.Header, payload}
+			}))
+			defer server.Close()
+			previous := apnsDevClient
+			apnsDevClient = &apns2.Client{Host: server.URL, HTTPClient: server.Client()}
+			defer func() { apnsDevClient = previous }()
+
+			sendPush(1, "bundle", PushRequest{DeviceToken: "device", Environment: "development", Title: "Tomorrowland", Body: tt.body})
+
+			var got push
+			select {
+			case got = <-pushes:
+			default:
+				t.Fatal("no push was sent")
+			}
+			if pushType := got.header.Get("apns-push-type"); pushType != tt.pushType {
+				t.Errorf("apns-push-type = %q, want %q", pushType, tt.pushType)
+			}
+			if priority := got.header.Get("apns-priority"); priority != tt.priority {
+				t.Errorf("apns-priority = %q, want %q", priority, tt.priority)
+			}
+			if !strings.Contains(string(got.payload), `"badge":1`) {
+				t.Errorf("payload %s doesn't set the badge", got.payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff computes exponentially growing retry delays with jitter, capped at Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+
+	attempt int
+}
+
+// Next returns the delay before the next attempt. The delay doubles on each call and
+// is randomized between half and all of its value so clients don't retry in lockstep.
+func (b *Backoff) Next() time.Duration {
+	delay := b.Initial << uint(b.attempt)
+	if delay <= 0 || delay > b.Max {
+		delay = b.Max
+	} else {
+		b.attempt++
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Reset starts the delays over from Initial
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops retries after Threshold consecutive failures for Cooldown,
+// then lets a single attempt through to decide whether to close again
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.RWMutex
+	failures int
+	openedAt time.Time
+}
+
+// RecordSuccess closes the breaker
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.openedAt = time.Time{}
+}
+
+// RecordFailure counts a failure and opens the breaker once the threshold is reached.
+// A failure while half-open re-opens it for another cooldown.
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures++
+	if cb.failures >= cb.Threshold {
+		cb.openedAt = time.Now()
+	}
+}
+
+// RemainingCooldown returns how long until the breaker lets the next attempt through
+func (cb *CircuitBreaker) RemainingCooldown() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if cb.openedAt.IsZero() {
+		return 0
+	}
+	if remaining := cb.Cooldown - time.Since(cb.openedAt); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// State returns the breaker's current state
+func (cb *CircuitBreaker) State() string {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	switch {
+	case cb.openedAt.IsZero():
+		return BreakerClosed
+	case time.Since(cb.openedAt) < cb.Cooldown:
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+// GetStats returns the breaker's state for the metrics endpoint
+func (cb *CircuitBreaker) GetStats() map[string]interface{} {
+	state := cb.State()
+
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	stats := map[string]interface{}{
+		"state":                state,
+		"consecutive_failures": cb.failures,
+		"threshold":            cb.Threshold,
+		"cooldown_seconds":     cb.Cooldown.Seconds(),
+	}
+	if !cb.openedAt.IsZero() {
+		stats["opened_at"] = cb.openedAt
+	}
+	return stats
+}
@@ -176,4 +176,159 @@ func (c *CachedDB) StoreAPNSReceipt(receipt APNSReceipt) error {
 // GetAPNSReceipts retrieves APNS receipts from the database (no caching for receipts)
 func (c *CachedDB) GetAPNSReceipts(limit int) ([]APNSReceipt, error) {
 	return c.db.GetAPNSReceipts(limit)
-} 
\ No newline at end of file
+}
+
+// AddSubscription saves a subscription in the database (no caching for subscriptions)
+func (c *CachedDB) AddSubscription(subscription Subscription) error {
+	return c.db.AddSubscription(subscription)
+}
+
+// RemoveSubscription deletes a subscription from the database (no caching for subscriptions)
+func (c *CachedDB) RemoveSubscription(token string, id int64) error {
+	return c.db.RemoveSubscription(token, id)
+}
+
+// GetSubscriptions retrieves a device's subscriptions from the database (no caching for subscriptions)
+func (c *CachedDB) GetSubscriptions(token string) ([]Subscription, error) {
+	return c.db.GetSubscriptions(token)
+}
+
+// GetSubscribedDevices retrieves the subscriptions matching an entity or its parks, with their devices, from the database (no caching for subscriptions)
+func (c *CachedDB) GetSubscribedDevices(entityID string, parkIDs []string) ([]SubscribedDevice, error) {
+	return c.db.GetSubscribedDevices(entityID, parkIDs)
+}
+
+// AddWaitTimeAlert saves a wait time alert in the database (no caching for alerts)
+func (c *CachedDB) AddWaitTimeAlert(alert WaitTimeAlert) error {
+	return c.db.AddWaitTimeAlert(alert)
+}
+
+// RemoveWaitTimeAlert deletes a wait time alert from the database (no caching for alerts)
+func (c *CachedDB) RemoveWaitTimeAlert(token string, id int64) error {
+	return c.db.RemoveWaitTimeAlert(token, id)
+}
+
+// GetWaitTimeAlerts retrieves a device's wait time alerts from the database (no caching for alerts)
+func (c *CachedDB) GetWaitTimeAlerts(token string) ([]WaitTimeAlert, error) {
+	return c.db.GetWaitTimeAlerts(token)
+}
+
+// GetWaitTimeAlertsForEntity retrieves the wait time alerts for an entity from the database (no caching for alerts)
+func (c *CachedDB) GetWaitTimeAlertsForEntity(entityID string) ([]WaitTimeAlert, error) {
+	return c.db.GetWaitTimeAlertsForEntity(entityID)
+}
+
+// SetWaitTimeAlertTriggered updates an alert's triggered state in the database (no caching for alerts)
+func (c *CachedDB) SetWaitTimeAlertTriggered(id int64, triggered bool) error {
+	return c.db.SetWaitTimeAlertTriggered(id, triggered)
+}
+
+// AddBoardingGroupAlert saves a boarding group alert in the database (no caching for alerts)
+func (c *CachedDB) AddBoardingGroupAlert(alert BoardingGroupAlert) error {
+	return c.db.AddBoardingGroupAlert(alert)
+}
+
+// RemoveBoardingGroupAlert deletes a boarding group alert from the database (no caching for alerts)
+func (c *CachedDB) RemoveBoardingGroupAlert(token string, id int64) error {
+	return c.db.RemoveBoardingGroupAlert(token, id)
+}
+
+// GetBoardingGroupAlerts retrieves a device's boarding group alerts from the database (no caching for alerts)
+func (c *CachedDB) GetBoardingGroupAlerts(token string) ([]BoardingGroupAlert, error) {
+	return c.db.GetBoardingGroupAlerts(token)
+}
+
+// GetBoardingGroupAlertsForEntity retrieves the boarding group alerts for an entity from the database (no caching for alerts)
+func (c *CachedDB) GetBoardingGroupAlertsForEntity(entityID string) ([]BoardingGroupAlert, error) {
+	return c.db.GetBoardingGroupAlertsForEntity(entityID)
+}
+
+// SetBoardingGroupAlertTriggered updates an alert's triggered state in the database (no caching for alerts)
+func (c *CachedDB) SetBoardingGroupAlertTriggered(id int64, triggered bool) error {
+	return c.db.SetBoardingGroupAlertTriggered(id, triggered)
+}
+
+// StoreDeadLetter saves a dead letter in the database (no caching for dead letters)
+func (c *CachedDB) StoreDeadLetter(deadLetter DeadLetter) error {
+	return c.db.StoreDeadLetter(deadLetter)
+}
+
+// GetDeadLetters retrieves dead letters from the database (no caching for dead letters)
+func (c *CachedDB) GetDeadLetters(limit int) ([]DeadLetter, error) {
+	return c.db.GetDeadLetters(limit)
+}
+
+// GetDeadLetter retrieves a dead letter from the database (no caching for dead letters)
+func (c *CachedDB) GetDeadLetter(id int64) (*DeadLetter, error) {
+	return c.db.GetDeadLetter(id)
+}
+
+// DeleteDeadLetter deletes a dead letter from the database (no caching for dead letters)
+func (c *CachedDB) DeleteDeadLetter(id int64) error {
+	return c.db.DeleteDeadLetter(id)
+}
+
+// AddOutboxPushes stores pushes in the outbox (no caching for the outbox)
+func (c *CachedDB) AddOutboxPushes(reqs []PushRequest, leaseUntil time.Time) ([]PushRequest, error) {
+	return c.db.AddOutboxPushes(reqs, leaseUntil)
+}
+
+// LeaseOutboxPush leases a push in the outbox (no caching for the outbox)
+func (c *CachedDB) LeaseOutboxPush(id int64, leaseID int64, leaseUntil time.Time) (bool, error) {
+	return c.db.LeaseOutboxPush(id, leaseID, leaseUntil)
+}
+
+// GetExpiredOutboxPushes retrieves pushes with expired leases from the outbox (no caching for the outbox)
+func (c *CachedDB) GetExpiredOutboxPushes(before time.Time, limit int) ([]PushRequest, error) {
+	return c.db.GetExpiredOutboxPushes(before, limit)
+}
+
+// DeleteOutboxPush removes an acknowledged push from the outbox (no caching for the outbox)
+func (c *CachedDB) DeleteOutboxPush(id int64, leaseID int64) error {
+	return c.db.DeleteOutboxPush(id, leaseID)
+}
+
+// CountOutboxPushes counts the pushes in the outbox (no caching for the outbox)
+func (c *CachedDB) CountOutboxPushes() (int, error) {
+	return c.db.CountOutboxPushes()
+}
+
+// StoreEntityEvent saves an entity event in the database (no caching for events)
+func (c *CachedDB) StoreEntityEvent(event EntityEvent) error {
+	return c.db.StoreEntityEvent(event)
+}
+
+// GetEntityEvents retrieves an entity's events from the database (no caching for events)
+func (c *CachedDB) GetEntityEvents(entityID string, from time.Time, to time.Time, limit int, offset int) ([]EntityEvent, error) {
+	return c.db.GetEntityEvents(entityID, from, to, limit, offset)
+}
+
+// GetStatusChangeEvents retrieves status change events from the database (no caching for events)
+func (c *CachedDB) GetStatusChangeEvents(entityID string, parkID string, from time.Time, to time.Time) ([]EntityEvent, error) {
+	return c.db.GetStatusChangeEvents(entityID, parkID, from, to)
+}
+
+// GetParks retrieves the configured parks from the database (cached by ParkManager)
+func (c *CachedDB) GetParks() ([]Park, error) {
+	return c.db.GetParks()
+}
+
+// StorePark saves a park in the database (cached by ParkManager)
+func (c *CachedDB) StorePark(park Park) error {
+	return c.db.StorePark(park)
+}
+
+// DeletePark removes a park from the database (cached by ParkManager)
+func (c *CachedDB) DeletePark(parkID string) error {
+	return c.db.DeletePark(parkID)
+}
+
+// StoreParkSchedule saves a park's schedule in the database (no caching for schedules)
+func (c *CachedDB) StoreParkSchedule(parkID string, entries []ScheduleEntry) error {
+	return c.db.StoreParkSchedule(parkID, entries)
+}
+
+// GetParkSchedule retrieves park schedules from the database (no caching for schedules)
+func (c *CachedDB) GetParkSchedule(parkIDs []string, fromDate string, toDate string) ([]ScheduleEntry, error) {
+	return c.db.GetParkSchedule(parkIDs, fromDate, toDate)
+}
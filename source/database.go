@@ -2,9 +2,12 @@ package main
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -21,20 +24,59 @@ type Database interface {
 	GetAPNSMessages(limit int) ([]APNSMessage, error)
 	StoreAPNSReceipt(receipt APNSReceipt) error
 	GetAPNSReceipts(limit int) ([]APNSReceipt, error)
+	StoreDeadLetter(deadLetter DeadLetter) error
+	GetDeadLetters(limit int) ([]DeadLetter, error)
+	GetDeadLetter(id int64) (*DeadLetter, error)
+	DeleteDeadLetter(id int64) error
+	AddOutboxPushes(reqs []PushRequest, leaseUntil time.Time) ([]PushRequest, error)
+	LeaseOutboxPush(id int64, leaseID int64, leaseUntil time.Time) (bool, error)
+	GetExpiredOutboxPushes(before time.Time, limit int) ([]PushRequest, error)
+	DeleteOutboxPush(id int64, leaseID int64) error
+	CountOutboxPushes() (int, error)
+	AddSubscription(subscription Subscription) error
+	RemoveSubscription(token string, id int64) error
+	GetSubscriptions(token string) ([]Subscription, error)
+	GetSubscribedDevices(entityID string, parkIDs []string) ([]SubscribedDevice, error)
+	AddWaitTimeAlert(alert WaitTimeAlert) error
+	RemoveWaitTimeAlert(token string, id int64) error
+	GetWaitTimeAlerts(token string) ([]WaitTimeAlert, error)
+	GetWaitTimeAlertsForEntity(entityID string) ([]WaitTimeAlert, error)
+	SetWaitTimeAlertTriggered(id int64, triggered bool) error
+	AddBoardingGroupAlert(alert BoardingGroupAlert) error
+	RemoveBoardingGroupAlert(token string, id int64) error
+	GetBoardingGroupAlerts(token string) ([]BoardingGroupAlert, error)
+	GetBoardingGroupAlertsForEntity(entityID string) ([]BoardingGroupAlert, error)
+	SetBoardingGroupAlertTriggered(id int64, triggered bool) error
+	StoreEntityEvent(event EntityEvent) error
+	GetEntityEvents(entityID string, from time.Time, to time.Time, limit int, offset int) ([]EntityEvent, error)
+	GetStatusChangeEvents(entityID string, parkID string, from time.Time, to time.Time) ([]EntityEvent, error)
+	GetParks() ([]Park, error)
+	StorePark(park Park) error
+	DeletePark(parkID string) error
+	StoreParkSchedule(parkID string, entries []ScheduleEntry) error
+	GetParkSchedule(parkIDs []string, fromDate string, toDate string) ([]ScheduleEntry, error)
 }
 
+// deviceOwnedTables lists the tables whose rows belong to a device and are removed with it
+var deviceOwnedTables = []string{"subscriptions", "wait_time_alerts", "boarding_group_alerts", "apns_dead_letters", "push_outbox"}
+
 // SQLiteDB implements the Database interface using SQLite
 type SQLiteDB struct {
 	db *sql.DB
 }
 
-// NewSQLiteDB creates a new SQLite database connection
-func NewSQLiteDB() (*SQLiteDB, error) {
-	// Use /app/data directory in container, fallback to local directory
-	dbPath := "./devices.db"
+// dataDir returns the directory for persistent files: /app/data in the container,
+// falling back to the working directory
+func dataDir() string {
 	if _, err := os.Stat("/app/data"); err == nil {
-		dbPath = "/app/data/devices.db"
+		return "/app/data"
 	}
+	return "."
+}
+
+// NewSQLiteDB creates a new SQLite database connection
+func NewSQLiteDB() (*SQLiteDB, error) {
+	dbPath := filepath.Join(dataDir(), "devices.db")
 
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -62,6 +104,22 @@ func NewSQLiteDB() (*SQLiteDB, error) {
 		log.Printf("Note: environment column may already exist: %v", err)
 	}
 
+	// Add alerts and sound columns if they don't exist (for existing databases)
+	_, err = db.Exec(`ALTER TABLE devices ADD COLUMN alerts BOOLEAN NOT NULL DEFAULT 0`)
+	if err != nil {
+		log.Printf("Note: alerts column may already exist: %v", err)
+	}
+	_, err = db.Exec(`ALTER TABLE devices ADD COLUMN sound TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		log.Printf("Note: sound column may already exist: %v", err)
+	}
+
+	// Add locale column if it doesn't exist (for existing databases)
+	_, err = db.Exec(`ALTER TABLE devices ADD COLUMN locale TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		log.Printf("Note: locale column may already exist: %v", err)
+	}
+
 	// Create apns_messages table if it doesn't exist
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS apns_messages (
@@ -103,6 +161,233 @@ func NewSQLiteDB() (*SQLiteDB, error) {
 		return nil, fmt.Errorf("failed to create apns_receipts table: %v", err)
 	}
 
+	// Create apns_dead_letters table if it doesn't exist.
+	// payload holds the whole PushRequest as JSON so it can be re-driven unchanged.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS apns_dead_letters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_token TEXT NOT NULL,
+			event_type TEXT,
+			entity_id TEXT,
+			payload TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			last_error TEXT,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (device_token) REFERENCES devices(device_token)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create apns_dead_letters table: %v", err)
+	}
+
+	// Create push_outbox table if it doesn't exist.
+	// Every push waits here until it has been sent. lease_id increases each time the push is
+	// leased, so only the holder of the latest lease can renew or acknowledge it.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS push_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_token TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			lease_id INTEGER NOT NULL DEFAULT 1,
+			lease_until TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push_outbox table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_push_outbox_lease_until ON push_outbox(lease_until)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push_outbox index: %v", err)
+	}
+
+	// Create subscriptions table if it doesn't exist.
+	// A subscription targets either a single entity or a whole park, optionally narrowed
+	// by entity type, by the statuses the entity transitions into and by specific transitions.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_token TEXT NOT NULL,
+			entity_id TEXT NOT NULL DEFAULT '',
+			park_id TEXT NOT NULL DEFAULT '',
+			entity_type TEXT NOT NULL DEFAULT '',
+			statuses TEXT NOT NULL DEFAULT '',
+			transitions TEXT NOT NULL DEFAULT '',
+			queues TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (device_token) REFERENCES devices(device_token)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscriptions table: %v", err)
+	}
+
+	// Add filter columns if they don't exist (for existing databases)
+	for _, column := range []string{"park_id", "entity_type", "statuses", "transitions", "queues"} {
+		_, err = db.Exec(fmt.Sprintf(`ALTER TABLE subscriptions ADD COLUMN %s TEXT NOT NULL DEFAULT ''`, column))
+		if err != nil {
+			// Column might already exist, which is fine
+			log.Printf("Note: subscriptions.%s column may already exist: %v", column, err)
+		}
+	}
+
+	// Replace the entity-only uniqueness constraint with one covering every subscription target
+	_, err = db.Exec(`DROP INDEX IF EXISTS idx_subscriptions_device_entity`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to drop subscriptions index: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_subscriptions_target ON subscriptions(device_token, entity_id, park_id, entity_type)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscriptions index: %v", err)
+	}
+
+	// Fan-out looks subscriptions up by entity and by park
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_subscriptions_entity ON subscriptions(entity_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscriptions index: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_subscriptions_park ON subscriptions(park_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subscriptions index: %v", err)
+	}
+
+	// Create wait_time_alerts table if it doesn't exist.
+	// triggered is set once an alert fires and cleared when the wait rises back above the threshold.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS wait_time_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_token TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			threshold INTEGER NOT NULL,
+			triggered BOOLEAN NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (device_token) REFERENCES devices(device_token)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wait_time_alerts table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_wait_time_alerts_target ON wait_time_alerts(device_token, entity_id, threshold)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wait_time_alerts index: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_wait_time_alerts_entity ON wait_time_alerts(entity_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wait_time_alerts index: %v", err)
+	}
+
+	// Create boarding_group_alerts table if it doesn't exist.
+	// group_start and group_end are 0 for alerts on the virtual queue opening.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS boarding_group_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_token TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			group_start INTEGER NOT NULL DEFAULT 0,
+			group_end INTEGER NOT NULL DEFAULT 0,
+			triggered BOOLEAN NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			FOREIGN KEY (device_token) REFERENCES devices(device_token)
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boarding_group_alerts table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_boarding_group_alerts_target ON boarding_group_alerts(device_token, entity_id, group_start, group_end)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boarding_group_alerts index: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_boarding_group_alerts_entity ON boarding_group_alerts(entity_id)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boarding_group_alerts index: %v", err)
+	}
+
+	// Create entity_events table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS entity_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			entity_id TEXT NOT NULL,
+			park_id TEXT,
+			old_status TEXT,
+			new_status TEXT,
+			old_wait_time INTEGER,
+			new_wait_time INTEGER,
+			timestamp TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entity_events table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_entity_events_entity_time ON entity_events(entity_id, timestamp)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entity_events index: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_entity_events_park_time ON entity_events(park_id, timestamp)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entity_events index: %v", err)
+	}
+
+	// Create parks table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS parks (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			type TEXT,
+			timezone TEXT NOT NULL DEFAULT '',
+			enabled BOOLEAN NOT NULL DEFAULT 1,
+			entity_types TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parks table: %v", err)
+	}
+
+	// Add timezone column if it doesn't exist (for existing databases)
+	_, err = db.Exec(`ALTER TABLE parks ADD COLUMN timezone TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		// Column might already exist, which is fine
+		log.Printf("Note: parks.timezone column may already exist: %v", err)
+	}
+
+	// Add entity_types column if it doesn't exist (for existing databases)
+	_, err = db.Exec(`ALTER TABLE parks ADD COLUMN entity_types TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		// Column might already exist, which is fine
+		log.Printf("Note: parks.entity_types column may already exist: %v", err)
+	}
+
+	// Create park_schedules table if it doesn't exist
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS park_schedules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			park_id TEXT NOT NULL,
+			date TEXT NOT NULL,
+			type TEXT NOT NULL,
+			opening_time TEXT NOT NULL,
+			closing_time TEXT NOT NULL,
+			timezone TEXT,
+			description TEXT
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create park_schedules table: %v", err)
+	}
+
+	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_park_schedules_park_date ON park_schedules(park_id, date)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create park_schedules index: %v", err)
+	}
+
 	return &SQLiteDB{db: db}, nil
 }
 
@@ -111,7 +396,10 @@ type DeviceRegistration struct {
 	DeviceToken string    `json:"deviceToken"`
 	AppVersion  string    `json:"appVersion"`
 	DeviceType  string    `json:"deviceType"`
-	Environment string    `json:"environment"` // "development" or "production"
+	Environment string    `json:"environment"`      // "development" or "production"
+	Alerts      bool      `json:"alerts"`           // send visible alerts rather than silent background pushes
+	Sound       string    `json:"sound,omitempty"`  // alert sound, "default" if empty
+	Locale      string    `json:"locale,omitempty"` // preferred locale for alerts, e.g. "ja-JP"; English if empty
 	LastUpdated time.Time `json:"lastUpdated"`
 }
 
@@ -144,20 +432,98 @@ type APNSReceipt struct {
 	NewWaitTime int       `json:"newWaitTime"`
 }
 
+// DeadLetter is a push that kept failing with a transient APNS error until the
+// workers ran out of retries. Re-driving it puts Request back on the push queue.
+type DeadLetter struct {
+	ID        int64       `json:"id"`
+	Request   PushRequest `json:"request"`
+	Attempts  int         `json:"attempts"`
+	LastError string      `json:"lastError"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// Subscription represents a device following a specific entity or every entity in a park.
+// EntityType, Statuses and Transitions are optional filters; empty means "any".
+// Queues lists the queue types (e.g. RETURN_TIME, BOARDING_GROUP) to notify about when they open.
+type Subscription struct {
+	ID          int64              `json:"id"`
+	DeviceToken string             `json:"deviceToken"`
+	EntityID    string             `json:"entityId,omitempty"`
+	ParkID      string             `json:"parkId,omitempty"`
+	EntityType  string             `json:"entityType,omitempty"`
+	Statuses    []EntityStatus     `json:"statuses,omitempty"`
+	Transitions []StatusTransition `json:"transitions,omitempty"`
+	Queues      []string           `json:"queues,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+}
+
+// SubscribedDevice is a subscription together with the registered device it belongs to
+type SubscribedDevice struct {
+	Subscription Subscription
+	Device       DeviceRegistration
+}
+
+// StatusTransition matches a status change from one status to another.
+// An empty From or To matches any status, so {To: DOWN} means "any -> DOWN".
+type StatusTransition struct {
+	From EntityStatus `json:"from,omitempty"`
+	To   EntityStatus `json:"to,omitempty"`
+}
+
+// WaitTimeAlert represents a device's request to be notified when an entity's
+// standby wait drops to or below Threshold minutes
+type WaitTimeAlert struct {
+	ID          int64     `json:"id"`
+	DeviceToken string    `json:"deviceToken"`
+	EntityID    string    `json:"entityId"`
+	Threshold   int       `json:"threshold"`
+	Triggered   bool      `json:"triggered"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// BoardingGroupAlert represents a device's request to be notified when an entity's virtual
+// queue calls any boarding group from GroupStart to GroupEnd, or when the virtual queue
+// opens if GroupStart is 0
+type BoardingGroupAlert struct {
+	ID          int64     `json:"id"`
+	DeviceToken string    `json:"deviceToken"`
+	EntityID    string    `json:"entityId"`
+	GroupStart  int       `json:"groupStart,omitempty"`
+	GroupEnd    int       `json:"groupEnd,omitempty"`
+	Triggered   bool      `json:"triggered"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// EntityEvent records a change to an entity's status and/or wait time.
+// A status-only change has equal wait times, and a wait-only change has equal statuses.
+type EntityEvent struct {
+	ID          int64     `json:"id"`
+	EntityID    string    `json:"entityId"`
+	ParkID      string    `json:"parkId"`
+	OldStatus   string    `json:"oldStatus"`
+	NewStatus   string    `json:"newStatus"`
+	OldWaitTime int       `json:"oldWaitTime"`
+	NewWaitTime int       `json:"newWaitTime"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
 // StoreDeviceToken saves or updates a device token in the database
 func (s *SQLiteDB) StoreDeviceToken(registration DeviceRegistration) error {
 	// Always use server time for last_updated
 	now := time.Now().UTC()
 
 	_, err := s.db.Exec(`
-		INSERT INTO devices (device_token, app_version, device_type, environment, last_updated)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO devices (device_token, app_version, device_type, environment, alerts, sound, locale, last_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(device_token) DO UPDATE SET
 			app_version = excluded.app_version,
 			device_type = excluded.device_type,
 			environment = excluded.environment,
+			alerts = excluded.alerts,
+			sound = excluded.sound,
+			locale = excluded.locale,
 			last_updated = ?
-	`, registration.DeviceToken, registration.AppVersion, registration.DeviceType, registration.Environment, now, now)
+	`, registration.DeviceToken, registration.AppVersion, registration.DeviceType, registration.Environment, registration.Alerts, registration.Sound, registration.Locale, now, now)
 
 	if err != nil {
 		return fmt.Errorf("failed to store device token: %v", err)
@@ -170,10 +536,10 @@ func (s *SQLiteDB) StoreDeviceToken(registration DeviceRegistration) error {
 func (s *SQLiteDB) GetDeviceToken(token string) (*DeviceRegistration, error) {
 	var device DeviceRegistration
 	err := s.db.QueryRow(`
-		SELECT device_token, app_version, device_type, environment, last_updated
+		SELECT device_token, app_version, device_type, environment, alerts, sound, locale, last_updated
 		FROM devices
 		WHERE device_token = ?
-	`, token).Scan(&device.DeviceToken, &device.AppVersion, &device.DeviceType, &device.Environment, &device.LastUpdated)
+	`, token).Scan(&device.DeviceToken, &device.AppVersion, &device.DeviceType, &device.Environment, &device.Alerts, &device.Sound, &device.Locale, &device.LastUpdated)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -188,7 +554,7 @@ func (s *SQLiteDB) GetDeviceToken(token string) (*DeviceRegistration, error) {
 // GetAllDevices returns all registered devices
 func (s *SQLiteDB) GetAllDevices() ([]DeviceRegistration, error) {
 	rows, err := s.db.Query(`
-		SELECT device_token, app_version, device_type, environment, last_updated
+		SELECT device_token, app_version, device_type, environment, alerts, sound, locale, last_updated
 		FROM devices
 		ORDER BY last_updated DESC
 	`)
@@ -200,7 +566,7 @@ func (s *SQLiteDB) GetAllDevices() ([]DeviceRegistration, error) {
 	var devices []DeviceRegistration
 	for rows.Next() {
 		var device DeviceRegistration
-		err := rows.Scan(&device.DeviceToken, &device.AppVersion, &device.DeviceType, &device.Environment, &device.LastUpdated)
+		err := rows.Scan(&device.DeviceToken, &device.AppVersion, &device.DeviceType, &device.Environment, &device.Alerts, &device.Sound, &device.Locale, &device.LastUpdated)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan device row: %v", err)
 		}
@@ -210,22 +576,36 @@ func (s *SQLiteDB) GetAllDevices() ([]DeviceRegistration, error) {
 	return devices, nil
 }
 
-// DeleteDeviceToken removes a device token from the database
+// DeleteDeviceToken removes a device token and everything it owns from the database
 func (s *SQLiteDB) DeleteDeviceToken(token string) error {
 	_, err := s.db.Exec("DELETE FROM devices WHERE device_token = ?", token)
 	if err != nil {
 		return fmt.Errorf("failed to delete device token: %v", err)
 	}
+
+	for _, table := range deviceOwnedTables {
+		_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE device_token = ?", table), token)
+		if err != nil {
+			return fmt.Errorf("failed to delete device rows from %s: %v", table, err)
+		}
+	}
 	return nil
 }
 
-// CleanupOldDevices removes devices that haven't been updated in a while
+// CleanupOldDevices removes devices that haven't been updated in a while, along with everything they own
 func (s *SQLiteDB) CleanupOldDevices(maxAge time.Duration) error {
 	cutoff := time.Now().UTC().Add(-maxAge)
 	_, err := s.db.Exec("DELETE FROM devices WHERE last_updated < ?", cutoff)
 	if err != nil {
 		return fmt.Errorf("failed to cleanup old devices: %v", err)
 	}
+
+	for _, table := range deviceOwnedTables {
+		_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE device_token NOT IN (SELECT device_token FROM devices)", table))
+		if err != nil {
+			return fmt.Errorf("failed to cleanup orphaned rows from %s: %v", table, err)
+		}
+	}
 	return nil
 }
 
@@ -307,4 +687,758 @@ func (s *SQLiteDB) GetAPNSReceipts(limit int) ([]APNSReceipt, error) {
 	}
 
 	return receipts, nil
-} 
\ No newline at end of file
+}
+
+// StoreDeadLetter saves a push that exhausted its retries
+func (s *SQLiteDB) StoreDeadLetter(deadLetter DeadLetter) error {
+	payload, err := json.Marshal(deadLetter.Request)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter payload: %v", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO apns_dead_letters (device_token, event_type, entity_id, payload, attempts, last_error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, deadLetter.Request.DeviceToken, deadLetter.Request.EventType, deadLetter.Request.EntityID, string(payload), deadLetter.Attempts, deadLetter.LastError, time.Now().UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store dead letter: %v", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetters retrieves a limited number of dead letters, newest first
+func (s *SQLiteDB) GetDeadLetters(limit int) ([]DeadLetter, error) {
+	rows, err := s.db.Query(`
+		SELECT id, payload, attempts, last_error, created_at
+		FROM apns_dead_letters
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []DeadLetter
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, *deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+// GetDeadLetter retrieves a single dead letter, or nil if it doesn't exist
+func (s *SQLiteDB) GetDeadLetter(id int64) (*DeadLetter, error) {
+	row := s.db.QueryRow(`
+		SELECT id, payload, attempts, last_error, created_at
+		FROM apns_dead_letters
+		WHERE id = ?
+	`, id)
+
+	deadLetter, err := scanDeadLetter(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return deadLetter, err
+}
+
+// DeleteDeadLetter removes a dead letter, e.g. once it has been re-driven
+func (s *SQLiteDB) DeleteDeadLetter(id int64) error {
+	_, err := s.db.Exec("DELETE FROM apns_dead_letters WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %v", err)
+	}
+	return nil
+}
+
+// AddOutboxPushes stores pushes in the outbox in one transaction, leased until leaseUntil,
+// and returns them with their outbox IDs and first lease ID set
+func (s *SQLiteDB) AddOutboxPushes(reqs []PushRequest, leaseUntil time.Time) ([]PushRequest, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin outbox transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO push_outbox (device_token, payload, lease_id, lease_until, created_at)
+		VALUES (?, ?, 1, ?, ?)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare outbox insert: %v", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+	stored := make([]PushRequest, 0, len(reqs))
+	for _, req := range reqs {
+		payload, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode outbox payload: %v", err)
+		}
+
+		result, err := stmt.Exec(req.DeviceToken, string(payload), leaseUntil.UTC(), now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store outbox push: %v", err)
+		}
+		req.OutboxID, err = result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get outbox push ID: %v", err)
+		}
+		req.LeaseID = 1
+		stored = append(stored, req)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit outbox pushes: %v", err)
+	}
+	return stored, nil
+}
+
+// LeaseOutboxPush takes a new lease on a push, until leaseUntil, if leaseID is still its latest
+// lease. The new lease's ID is leaseID+1. It returns false if the push has been leased again
+// since, or has already been acknowledged.
+func (s *SQLiteDB) LeaseOutboxPush(id int64, leaseID int64, leaseUntil time.Time) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE push_outbox SET lease_id = lease_id + 1, lease_until = ?
+		WHERE id = ? AND lease_id = ?
+	`, leaseUntil.UTC(), id, leaseID)
+	if err != nil {
+		return false, fmt.Errorf("failed to lease outbox push: %v", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to lease outbox push: %v", err)
+	}
+	return rows == 1, nil
+}
+
+// GetExpiredOutboxPushes returns the oldest pushes whose lease ended before the given time,
+// with their outbox and lease IDs set
+func (s *SQLiteDB) GetExpiredOutboxPushes(before time.Time, limit int) ([]PushRequest, error) {
+	rows, err := s.db.Query(`
+		SELECT id, lease_id, payload
+		FROM push_outbox
+		WHERE lease_until < ?
+		ORDER BY id
+		LIMIT ?
+	`, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox pushes: %v", err)
+	}
+	defer rows.Close()
+
+	var pushes []PushRequest
+	for rows.Next() {
+		var id, leaseID int64
+		var payload string
+		if err := rows.Scan(&id, &leaseID, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox push row: %v", err)
+		}
+
+		var req PushRequest
+		if err := json.Unmarshal([]byte(payload), &req); err != nil {
+			log.Printf("Skipping outbox push %d with unreadable payload: %v", id, err)
+			continue
+		}
+		req.OutboxID = id
+		req.LeaseID = leaseID
+		pushes = append(pushes, req)
+	}
+
+	return pushes, nil
+}
+
+// DeleteOutboxPush acknowledges a push, removing it from the outbox if leaseID is still its latest lease
+func (s *SQLiteDB) DeleteOutboxPush(id int64, leaseID int64) error {
+	_, err := s.db.Exec("DELETE FROM push_outbox WHERE id = ? AND lease_id = ?", id, leaseID)
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox push: %v", err)
+	}
+	return nil
+}
+
+// CountOutboxPushes returns the number of pushes waiting in the outbox
+func (s *SQLiteDB) CountOutboxPushes() (int, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM push_outbox").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count outbox pushes: %v", err)
+	}
+	return count, nil
+}
+
+// scanDeadLetter reads a dead letter row and decodes its stored push request
+func scanDeadLetter(row interface{ Scan(dest ...any) error }) (*DeadLetter, error) {
+	var deadLetter DeadLetter
+	var payload string
+	var lastError sql.NullString
+	err := row.Scan(&deadLetter.ID, &payload, &deadLetter.Attempts, &lastError, &deadLetter.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan dead letter row: %v", err)
+	}
+	deadLetter.LastError = lastError.String
+
+	if err := json.Unmarshal([]byte(payload), &deadLetter.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter %d payload: %v", deadLetter.ID, err)
+	}
+	return &deadLetter, nil
+}
+
+// AddSubscription subscribes a device to an entity or park. Subscribing to the same
+// target again replaces its status, transition and queue filters.
+func (s *SQLiteDB) AddSubscription(subscription Subscription) error {
+	_, err := s.db.Exec(`
+		INSERT INTO subscriptions (device_token, entity_id, park_id, entity_type, statuses, transitions, queues, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(device_token, entity_id, park_id, entity_type) DO UPDATE SET
+			statuses = excluded.statuses,
+			transitions = excluded.transitions,
+			queues = excluded.queues
+	`, subscription.DeviceToken, subscription.EntityID, subscription.ParkID, subscription.EntityType,
+		encodeStatuses(subscription.Statuses), encodeTransitions(subscription.Transitions),
+		strings.Join(subscription.Queues, ","), time.Now().UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store subscription: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveSubscription deletes one of a device's subscriptions
+func (s *SQLiteDB) RemoveSubscription(token string, id int64) error {
+	_, err := s.db.Exec("DELETE FROM subscriptions WHERE device_token = ? AND id = ?", token, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete subscription: %v", err)
+	}
+	return nil
+}
+
+// GetSubscriptions returns all subscriptions for a device
+func (s *SQLiteDB) GetSubscriptions(token string) ([]Subscription, error) {
+	rows, err := s.db.Query(`
+		SELECT id, device_token, entity_id, park_id, entity_type, statuses, transitions, queues, created_at
+		FROM subscriptions
+		WHERE device_token = ?
+		ORDER BY created_at DESC
+	`, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %v", err)
+	}
+	defer rows.Close()
+
+	return scanSubscriptions(rows)
+}
+
+// GetSubscribedDevices returns the subscriptions targeting an entity or any of the given
+// parks, e.g. the entity's park and the configured park that park belongs to, each with its
+// device. Subscriptions whose device is no longer registered are left out.
+// Entity type and status filters are left for the caller to evaluate.
+func (s *SQLiteDB) GetSubscribedDevices(entityID string, parkIDs []string) ([]SubscribedDevice, error) {
+	args := []interface{}{entityID}
+	parkClause := ""
+	if len(parkIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(parkIDs)), ",")
+		parkClause = fmt.Sprintf(" OR (s.park_id != '' AND s.park_id IN (%s))", placeholders)
+		for _, parkID := range parkIDs {
+			args = append(args, parkID)
+		}
+	}
+
+	rows, err := s.db.Query(`
+		SELECT s.id, s.device_token, s.entity_id, s.park_id, s.entity_type, s.statuses, s.transitions, s.queues, s.created_at,
+			d.app_version, d.device_type, d.environment, d.alerts, d.sound, d.locale, d.last_updated
+		FROM subscriptions s
+		JOIN devices d ON d.device_token = s.device_token
+		WHERE (s.entity_id != '' AND s.entity_id = ?)`+parkClause+`
+		ORDER BY s.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscribed devices: %v", err)
+	}
+	defer rows.Close()
+
+	var subscribed []SubscribedDevice
+	for rows.Next() {
+		var sd SubscribedDevice
+		var statuses, transitions, queues string
+		subscription, device := &sd.Subscription, &sd.Device
+		err := rows.Scan(&subscription.ID, &subscription.DeviceToken, &subscription.EntityID, &subscription.ParkID, &subscription.EntityType, &statuses, &transitions, &queues, &subscription.CreatedAt,
+			&device.AppVersion, &device.DeviceType, &device.Environment, &device.Alerts, &device.Sound, &device.Locale, &device.LastUpdated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscribed device row: %v", err)
+		}
+		subscription.Statuses = decodeStatuses(statuses)
+		subscription.Transitions = decodeTransitions(transitions)
+		if queues != "" {
+			subscription.Queues = strings.Split(queues, ",")
+		}
+		device.DeviceToken = subscription.DeviceToken
+		subscribed = append(subscribed, sd)
+	}
+
+	return subscribed, nil
+}
+
+// scanSubscriptions reads subscription rows into Subscription structs
+func scanSubscriptions(rows *sql.Rows) ([]Subscription, error) {
+	var subscriptions []Subscription
+	for rows.Next() {
+		var subscription Subscription
+		var statuses, transitions, queues string
+		err := rows.Scan(&subscription.ID, &subscription.DeviceToken, &subscription.EntityID, &subscription.ParkID, &subscription.EntityType, &statuses, &transitions, &queues, &subscription.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subscription row: %v", err)
+		}
+		subscription.Statuses = decodeStatuses(statuses)
+		subscription.Transitions = decodeTransitions(transitions)
+		if queues != "" {
+			subscription.Queues = strings.Split(queues, ",")
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// encodeStatuses stores a status filter as a comma-separated list
+func encodeStatuses(statuses []EntityStatus) string {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+	return strings.Join(values, ",")
+}
+
+// decodeStatuses parses a comma-separated status filter
+func decodeStatuses(value string) []EntityStatus {
+	if value == "" {
+		return nil
+	}
+	var statuses []EntityStatus
+	for _, status := range strings.Split(value, ",") {
+		statuses = append(statuses, EntityStatus(status))
+	}
+	return statuses
+}
+
+// encodeTransitions stores a transition filter as a comma-separated list of FROM>TO pairs
+func encodeTransitions(transitions []StatusTransition) string {
+	values := make([]string, len(transitions))
+	for i, transition := range transitions {
+		values[i] = string(transition.From) + ">" + string(transition.To)
+	}
+	return strings.Join(values, ",")
+}
+
+// decodeTransitions parses a comma-separated list of FROM>TO pairs
+func decodeTransitions(value string) []StatusTransition {
+	if value == "" {
+		return nil
+	}
+	var transitions []StatusTransition
+	for _, pair := range strings.Split(value, ",") {
+		from, to, _ := strings.Cut(pair, ">")
+		transitions = append(transitions, StatusTransition{From: EntityStatus(from), To: EntityStatus(to)})
+	}
+	return transitions
+}
+
+// AddWaitTimeAlert stores a wait time alert for a device. Adding the same alert again re-arms it.
+func (s *SQLiteDB) AddWaitTimeAlert(alert WaitTimeAlert) error {
+	_, err := s.db.Exec(`
+		INSERT INTO wait_time_alerts (device_token, entity_id, threshold, triggered, created_at)
+		VALUES (?, ?, ?, 0, ?)
+		ON CONFLICT(device_token, entity_id, threshold) DO UPDATE SET
+			triggered = 0
+	`, alert.DeviceToken, alert.EntityID, alert.Threshold, time.Now().UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store wait time alert: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveWaitTimeAlert deletes one of a device's wait time alerts
+func (s *SQLiteDB) RemoveWaitTimeAlert(token string, id int64) error {
+	_, err := s.db.Exec("DELETE FROM wait_time_alerts WHERE device_token = ? AND id = ?", token, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete wait time alert: %v", err)
+	}
+	return nil
+}
+
+// GetWaitTimeAlerts returns all wait time alerts for a device
+func (s *SQLiteDB) GetWaitTimeAlerts(token string) ([]WaitTimeAlert, error) {
+	rows, err := s.db.Query(`
+		SELECT id, device_token, entity_id, threshold, triggered, created_at
+		FROM wait_time_alerts
+		WHERE device_token = ?
+		ORDER BY created_at DESC
+	`, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wait time alerts: %v", err)
+	}
+	defer rows.Close()
+
+	return scanWaitTimeAlerts(rows)
+}
+
+// GetWaitTimeAlertsForEntity returns all wait time alerts watching an entity
+func (s *SQLiteDB) GetWaitTimeAlertsForEntity(entityID string) ([]WaitTimeAlert, error) {
+	rows, err := s.db.Query(`
+		SELECT id, device_token, entity_id, threshold, triggered, created_at
+		FROM wait_time_alerts
+		WHERE entity_id = ?
+	`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wait time alerts: %v", err)
+	}
+	defer rows.Close()
+
+	return scanWaitTimeAlerts(rows)
+}
+
+// SetWaitTimeAlertTriggered records whether an alert has fired since the wait was last above its threshold
+func (s *SQLiteDB) SetWaitTimeAlertTriggered(id int64, triggered bool) error {
+	_, err := s.db.Exec("UPDATE wait_time_alerts SET triggered = ? WHERE id = ?", triggered, id)
+	if err != nil {
+		return fmt.Errorf("failed to update wait time alert: %v", err)
+	}
+	return nil
+}
+
+// scanWaitTimeAlerts reads wait time alert rows into WaitTimeAlert structs
+func scanWaitTimeAlerts(rows *sql.Rows) ([]WaitTimeAlert, error) {
+	var alerts []WaitTimeAlert
+	for rows.Next() {
+		var alert WaitTimeAlert
+		err := rows.Scan(&alert.ID, &alert.DeviceToken, &alert.EntityID, &alert.Threshold, &alert.Triggered, &alert.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wait time alert row: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// AddBoardingGroupAlert stores a boarding group alert for a device. Adding the same alert again re-arms it.
+func (s *SQLiteDB) AddBoardingGroupAlert(alert BoardingGroupAlert) error {
+	_, err := s.db.Exec(`
+		INSERT INTO boarding_group_alerts (device_token, entity_id, group_start, group_end, triggered, created_at)
+		VALUES (?, ?, ?, ?, 0, ?)
+		ON CONFLICT(device_token, entity_id, group_start, group_end) DO UPDATE SET
+			triggered = 0
+	`, alert.DeviceToken, alert.EntityID, alert.GroupStart, alert.GroupEnd, time.Now().UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store boarding group alert: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveBoardingGroupAlert deletes one of a device's boarding group alerts
+func (s *SQLiteDB) RemoveBoardingGroupAlert(token string, id int64) error {
+	_, err := s.db.Exec("DELETE FROM boarding_group_alerts WHERE device_token = ? AND id = ?", token, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete boarding group alert: %v", err)
+	}
+	return nil
+}
+
+// GetBoardingGroupAlerts returns all boarding group alerts for a device
+func (s *SQLiteDB) GetBoardingGroupAlerts(token string) ([]BoardingGroupAlert, error) {
+	rows, err := s.db.Query(`
+		SELECT id, device_token, entity_id, group_start, group_end, triggered, created_at
+		FROM boarding_group_alerts
+		WHERE device_token = ?
+		ORDER BY created_at DESC
+	`, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query boarding group alerts: %v", err)
+	}
+	defer rows.Close()
+
+	return scanBoardingGroupAlerts(rows)
+}
+
+// GetBoardingGroupAlertsForEntity returns all boarding group alerts watching an entity
+func (s *SQLiteDB) GetBoardingGroupAlertsForEntity(entityID string) ([]BoardingGroupAlert, error) {
+	rows, err := s.db.Query(`
+		SELECT id, device_token, entity_id, group_start, group_end, triggered, created_at
+		FROM boarding_group_alerts
+		WHERE entity_id = ?
+	`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query boarding group alerts: %v", err)
+	}
+	defer rows.Close()
+
+	return scanBoardingGroupAlerts(rows)
+}
+
+// SetBoardingGroupAlertTriggered records whether an alert has fired since it was last re-armed
+func (s *SQLiteDB) SetBoardingGroupAlertTriggered(id int64, triggered bool) error {
+	_, err := s.db.Exec("UPDATE boarding_group_alerts SET triggered = ? WHERE id = ?", triggered, id)
+	if err != nil {
+		return fmt.Errorf("failed to update boarding group alert: %v", err)
+	}
+	return nil
+}
+
+// scanBoardingGroupAlerts reads boarding group alert rows into BoardingGroupAlert structs
+func scanBoardingGroupAlerts(rows *sql.Rows) ([]BoardingGroupAlert, error) {
+	var alerts []BoardingGroupAlert
+	for rows.Next() {
+		var alert BoardingGroupAlert
+		err := rows.Scan(&alert.ID, &alert.DeviceToken, &alert.EntityID, &alert.GroupStart, &alert.GroupEnd, &alert.Triggered, &alert.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan boarding group alert row: %v", err)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// StoreEntityEvent saves an entity status/wait time change in the database
+func (s *SQLiteDB) StoreEntityEvent(event EntityEvent) error {
+	_, err := s.db.Exec(`
+		INSERT INTO entity_events (entity_id, park_id, old_status, new_status, old_wait_time, new_wait_time, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, event.EntityID, event.ParkID, event.OldStatus, event.NewStatus, event.OldWaitTime, event.NewWaitTime, event.Timestamp.UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store entity event: %v", err)
+	}
+
+	return nil
+}
+
+// GetEntityEvents returns an entity's events between from and to, newest first.
+// A zero from or to leaves that end of the range open.
+func (s *SQLiteDB) GetEntityEvents(entityID string, from time.Time, to time.Time, limit int, offset int) ([]EntityEvent, error) {
+	query := `
+		SELECT id, entity_id, park_id, old_status, new_status, old_wait_time, new_wait_time, timestamp
+		FROM entity_events
+		WHERE entity_id = ?`
+	args := []interface{}{entityID}
+
+	if !from.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		query += " AND timestamp <= ?"
+		args = append(args, to.UTC())
+	}
+
+	query += " ORDER BY timestamp DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entity events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []EntityEvent
+	for rows.Next() {
+		var event EntityEvent
+		err := rows.Scan(&event.ID, &event.EntityID, &event.ParkID, &event.OldStatus, &event.NewStatus, &event.OldWaitTime, &event.NewWaitTime, &event.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entity event row: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// GetStatusChangeEvents returns the status changes between from and to, oldest first,
+// for a single entity or, when entityID is empty, for every entity in a park
+func (s *SQLiteDB) GetStatusChangeEvents(entityID string, parkID string, from time.Time, to time.Time) ([]EntityEvent, error) {
+	column, value := "entity_id", entityID
+	if entityID == "" {
+		column, value = "park_id", parkID
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT id, entity_id, park_id, old_status, new_status, old_wait_time, new_wait_time, timestamp
+		FROM entity_events
+		WHERE %s = ? AND old_status != new_status AND timestamp >= ? AND timestamp <= ?
+		ORDER BY timestamp ASC
+	`, column), value, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status change events: %v", err)
+	}
+	defer rows.Close()
+
+	var events []EntityEvent
+	for rows.Next() {
+		var event EntityEvent
+		err := rows.Scan(&event.ID, &event.EntityID, &event.ParkID, &event.OldStatus, &event.NewStatus, &event.OldWaitTime, &event.NewWaitTime, &event.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entity event row: %v", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// GetParks returns all configured parks in the order they were added
+func (s *SQLiteDB) GetParks() ([]Park, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, type, timezone, enabled, entity_types
+		FROM parks
+		ORDER BY created_at ASC, rowid ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query parks: %v", err)
+	}
+	defer rows.Close()
+
+	var parks []Park
+	for rows.Next() {
+		var park Park
+		var entityTypes string
+		err := rows.Scan(&park.ID, &park.Name, &park.Type, &park.Timezone, &park.Enabled, &entityTypes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan park row: %v", err)
+		}
+		if entityTypes != "" {
+			park.EntityTypes = strings.Split(entityTypes, ",")
+		}
+		parks = append(parks, park)
+	}
+
+	return parks, nil
+}
+
+// StorePark saves or updates a park. An empty timezone keeps the one already stored.
+func (s *SQLiteDB) StorePark(park Park) error {
+	_, err := s.db.Exec(`
+		INSERT INTO parks (id, name, type, timezone, enabled, entity_types, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			type = excluded.type,
+			timezone = COALESCE(NULLIF(excluded.timezone, ''), parks.timezone),
+			enabled = excluded.enabled,
+			entity_types = excluded.entity_types
+	`, park.ID, park.Name, park.Type, park.Timezone, park.Enabled, strings.Join(park.EntityTypes, ","), time.Now().UTC())
+
+	if err != nil {
+		return fmt.Errorf("failed to store park: %v", err)
+	}
+
+	return nil
+}
+
+// DeletePark removes a park
+func (s *SQLiteDB) DeletePark(parkID string) error {
+	_, err := s.db.Exec("DELETE FROM parks WHERE id = ?", parkID)
+	if err != nil {
+		return fmt.Errorf("failed to delete park: %v", err)
+	}
+	return nil
+}
+
+// StoreParkSchedule replaces a park's schedule from the earliest date in entries onwards
+func (s *SQLiteDB) StoreParkSchedule(parkID string, entries []ScheduleEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	earliest := entries[0].Date
+	for _, entry := range entries {
+		if entry.Date < earliest {
+			earliest = entry.Date
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin park schedule transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM park_schedules WHERE park_id = ? AND date >= ?", parkID, earliest); err != nil {
+		return fmt.Errorf("failed to clear park schedule: %v", err)
+	}
+
+	for _, entry := range entries {
+		_, err := tx.Exec(`
+			INSERT INTO park_schedules (park_id, date, type, opening_time, closing_time, timezone, description)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, parkID, entry.Date, entry.Type, entry.OpeningTime, entry.ClosingTime, entry.Timezone, entry.Description)
+		if err != nil {
+			return fmt.Errorf("failed to store park schedule: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit park schedule: %v", err)
+	}
+
+	return nil
+}
+
+// GetParkSchedule returns the schedule entries for the given parks between two
+// YYYY-MM-DD dates (inclusive). An empty date leaves that end of the range open.
+func (s *SQLiteDB) GetParkSchedule(parkIDs []string, fromDate string, toDate string) ([]ScheduleEntry, error) {
+	if len(parkIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(parkIDs)), ",")
+	query := fmt.Sprintf(`
+		SELECT park_id, date, type, opening_time, closing_time, timezone, description
+		FROM park_schedules
+		WHERE park_id IN (%s)`, placeholders)
+	var args []interface{}
+	for _, parkID := range parkIDs {
+		args = append(args, parkID)
+	}
+
+	if fromDate != "" {
+		query += " AND date >= ?"
+		args = append(args, fromDate)
+	}
+	if toDate != "" {
+		query += " AND date <= ?"
+		args = append(args, toDate)
+	}
+	query += " ORDER BY date ASC, opening_time ASC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query park schedule: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []ScheduleEntry
+	for rows.Next() {
+		var entry ScheduleEntry
+		err := rows.Scan(&entry.ParkID, &entry.Date, &entry.Type, &entry.OpeningTime, &entry.ClosingTime, &entry.Timezone, &entry.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan park schedule row: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// useTestDB points the global db at a fresh database in a temporary directory for the
+// rest of the test
+func useTestDB(t *testing.T) *SQLiteDB {
+	t.Helper()
+	if dataDir() != "." {
+		t.Skip("database would be created in the container's data directory")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	sqliteDB, err := NewSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqliteDB.db.Close() })
+
+	previous := db
+	db = sqliteDB
+	t.Cleanup(func() { db = previous })
+	return sqliteDB
+}
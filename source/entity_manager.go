@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"sync"
 	"time"
 )
@@ -15,6 +16,27 @@ const (
 	StatusRefurbishment EntityStatus = "REFURBISHMENT"
 )
 
+// isKnownStatus reports whether a status is one of the EntityStatus values above
+func isKnownStatus(status EntityStatus) bool {
+	switch status {
+	case StatusClosed, StatusOperating, StatusDown, StatusRefurbishment:
+		return true
+	}
+	return false
+}
+
+// Matches reports whether a change from oldStatus to newStatus satisfies the transition.
+// Empty sides of the transition act as wildcards.
+func (t StatusTransition) Matches(oldStatus, newStatus EntityStatus) bool {
+	if t.From != "" && t.From != oldStatus {
+		return false
+	}
+	if t.To != "" && t.To != newStatus {
+		return false
+	}
+	return true
+}
+
 // Entity represents a theme park attraction or other entity
 type Entity struct {
 	EntityID           string       `json:"entityId"`
@@ -25,6 +47,47 @@ type Entity struct {
 	Status            EntityStatus `json:"status"`
 	LastStatusChange  time.Time    `json:"lastStatusChange"`
 	LastWaitTimeChange time.Time    `json:"lastWaitTimeChange"`
+	OperatingHours    []OperatingHour `json:"operatingHours,omitempty"`
+	Queues            map[string]QueueData `json:"queues,omitempty"` // every queue by type, including STANDBY
+	Showtimes         []Showtime   `json:"showtimes,omitempty"` // performances, for SHOW entities
+	LastUpdated       time.Time    `json:"lastUpdated"` // when the upstream feed last updated the entity, zero if unknown
+}
+
+// OlderThan reports whether an update is an upstream snapshot older than what the other
+// entity already holds. Both times come from the upstream feed, so the server's clock
+// doesn't matter; updates without an upstream time are never considered older.
+func (e Entity) OlderThan(other Entity) bool {
+	return !e.LastUpdated.IsZero() && e.LastUpdated.Before(other.LastUpdated)
+}
+
+// scheduledCloseTolerance is how far either side of a scheduled closing time a
+// CLOSED transition is still treated as the scheduled close rather than an unexpected closure
+const scheduledCloseTolerance = 15 * time.Minute
+
+// isScheduledClosure reports whether closing at the given time is expected from a set of
+// operating hours: either near the end of an operating window or outside all of them.
+// Without operating hours a closure is never treated as scheduled.
+func isScheduledClosure(operatingHours []OperatingHour, at time.Time) bool {
+	if len(operatingHours) == 0 {
+		return false
+	}
+
+	for _, hours := range operatingHours {
+		start, err := time.Parse(time.RFC3339, hours.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, hours.EndTime)
+		if err != nil {
+			continue
+		}
+
+		// Closing well inside an operating window is unexpected
+		if at.After(start) && at.Before(end.Add(-scheduledCloseTolerance)) {
+			return false
+		}
+	}
+	return true
 }
 
 // EntityManager handles the thread-safe storage and updates of entities
@@ -61,50 +124,206 @@ func (em *EntityManager) GetAllEntities() map[string]Entity {
 	return result
 }
 
+// GetEntitiesByPark returns the entities belonging to any of the given park IDs
+func (em *EntityManager) GetEntitiesByPark(parkIDs ...string) []Entity {
+	wanted := make(map[string]bool, len(parkIDs))
+	for _, parkID := range parkIDs {
+		wanted[parkID] = true
+	}
+
+	var result []Entity
+	em.entities.Range(func(key, value interface{}) bool {
+		entity := value.(Entity)
+		if wanted[entity.ParkID] {
+			result = append(result, entity)
+		}
+		return true
+	})
+	return result
+}
+
+// RemoveEntitiesByPark removes the entities belonging to any of the given park IDs, e.g. once
+// a park is no longer ingested, and returns how many were removed
+func (em *EntityManager) RemoveEntitiesByPark(parkIDs ...string) int {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	removed := 0
+	for _, entity := range em.GetEntitiesByPark(parkIDs...) {
+		em.entities.Delete(entity.EntityID)
+		removed++
+	}
+	return removed
+}
+
+// RemoveUntrackedEntities removes the entities of a park, or of the given child parks, whose
+// type the park no longer tracks, and returns how many were removed
+func (em *EntityManager) RemoveUntrackedEntities(park Park, childParkIDs []string) int {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+
+	removed := 0
+	for _, entity := range em.GetEntitiesByPark(append([]string{park.ID}, childParkIDs...)...) {
+		if !park.Tracks(entity.EntityType) {
+			em.entities.Delete(entity.EntityID)
+			removed++
+		}
+	}
+	return removed
+}
+
 // ProcessEntity processes an entity update from the queue
 func (em *EntityManager) ProcessEntity(entity Entity) {
+	now := time.Now()
+	changes := em.apply(entity, now)
+
+	// Judging a closure can fall back to the park's schedule in the database, and recording
+	// history writes to it, so both wait until the lock is released rather than holding up
+	// other updates. The closure is judged from the entity as this update left it.
+	if changes.status != nil {
+		if changes.status.NewStatus == StatusClosed {
+			changes.status.Scheduled = isScheduledClosure(scheduledHours(changes.entity, now), now)
+		}
+		messageBus.PublishStatus(*changes.status)
+	}
+	if changes.event != nil {
+		if err := db.StoreEntityEvent(*changes.event); err != nil {
+			log.Printf("Failed to store entity event for %s: %v", entity.EntityID, err)
+		}
+	}
+}
+
+// entityChanges is what applying an update changed that is published or recorded once the
+// entity lock has been released
+type entityChanges struct {
+	entity Entity               // the entity as the update left it
+	status *StatusChangeMessage // set if the status changed
+	event  *EntityEvent         // set if the status or wait time changed
+}
+
+// apply applies an update to the stored entity, publishing its wait time and queue changes
+func (em *EntityManager) apply(entity Entity, now time.Time) entityChanges {
 	em.mu.Lock()
 	defer em.mu.Unlock()
 
 	existing, exists := em.entities.Load(entity.EntityID)
 	if !exists {
-		now := time.Now()
 		entity.LastStatusChange = now
 		entity.LastWaitTimeChange = now
 		em.entities.Store(entity.EntityID, entity)
-		return
+		return entityChanges{entity: entity}
 	}
 
 	// Convert existing to Entity type
 	existingEntity := existing.(Entity)
+	previous := existingEntity
+
+	// Drop updates the upstream feed had already superseded, e.g. a REST snapshot taken
+	// before the WebSocket update we applied last
+	if entity.OlderThan(existingEntity) {
+		return entityChanges{entity: existingEntity}
+	}
+	if entity.LastUpdated.After(existingEntity.LastUpdated) {
+		existingEntity.LastUpdated = entity.LastUpdated
+	}
+
+	// Keep the latest operating hours when the update carries them
+	if len(entity.OperatingHours) > 0 {
+		existingEntity.OperatingHours = entity.OperatingHours
+	}
+
+	// An empty list of showtimes is meaningful (every performance is over or cancelled)
+	if entity.Showtimes != nil {
+		existingEntity.Showtimes = entity.Showtimes
+	}
+
+	var changes entityChanges
 
 	// Check for status change
 	if entity.Status != existingEntity.Status {
-		messageBus.PublishStatus(StatusChangeMessage{
+		changes.status = &StatusChangeMessage{
 			EntityID:    entity.EntityID,
+			EntityName:  existingEntity.Name,
 			ParkID:      entity.ParkID,
+			EntityType:  entity.EntityType,
 			OldStatus:   existingEntity.Status,
 			NewStatus:   entity.Status,
 			OldWaitTime: existingEntity.WaitTime,
 			NewWaitTime: entity.WaitTime,
-			Timestamp:   time.Now(),
-		})
+			Timestamp:   now,
+		}
 		existingEntity.Status = entity.Status
-		existingEntity.LastStatusChange = time.Now()
+		existingEntity.LastStatusChange = now
 	}
 
 	// Check for wait time change
 	if entity.WaitTime != existingEntity.WaitTime {
-		// TODO: Re-enable when working on wait time functionality
-		// messageBus.PublishWaitTime(WaitTimeMessage{
-		// 	EntityID:    entity.EntityID,
-		// 	OldWaitTime: existingEntity.WaitTime,
-		// 	NewWaitTime: entity.WaitTime,
-		// 	Timestamp:   time.Now(),
-		// })
+		messageBus.PublishWaitTime(WaitTimeMessage{
+			EntityID:    entity.EntityID,
+			EntityName:  existingEntity.Name,
+			ParkID:      entity.ParkID,
+			Status:      existingEntity.Status,
+			OldWaitTime: existingEntity.WaitTime,
+			NewWaitTime: entity.WaitTime,
+			Timestamp:   now,
+		})
 		existingEntity.WaitTime = entity.WaitTime
-		existingEntity.LastWaitTimeChange = time.Now()
+		existingEntity.LastWaitTimeChange = now
+	}
+
+	// Check for queues opening, closing or calling new boarding groups. Entities
+	// stored without queue data have nothing to compare against.
+	if entity.Queues != nil {
+		if existingEntity.Queues != nil {
+			em.publishQueueChanges(existingEntity, entity.Queues, now)
+		}
+		existingEntity.Queues = entity.Queues
 	}
 
 	em.entities.Store(entity.EntityID, existingEntity)
-} 
\ No newline at end of file
+	changes.entity = existingEntity
+
+	// Record the change in the entity's history
+	if existingEntity.Status != previous.Status || existingEntity.WaitTime != previous.WaitTime {
+		changes.event = &EntityEvent{
+			EntityID:    entity.EntityID,
+			ParkID:      entity.ParkID,
+			OldStatus:   string(previous.Status),
+			NewStatus:   string(existingEntity.Status),
+			OldWaitTime: previous.WaitTime,
+			NewWaitTime: existingEntity.WaitTime,
+			Timestamp:   now,
+		}
+	}
+	return changes
+}
+
+// publishQueueChanges publishes a QueueChangeMessage for every queue whose state differs between
+// the entity's stored queues and an update. Queues missing from either side are treated as empty.
+func (em *EntityManager) publishQueueChanges(existing Entity, queues map[string]QueueData, now time.Time) {
+	queueTypes := make(map[string]bool)
+	for queueType := range existing.Queues {
+		queueTypes[queueType] = true
+	}
+	for queueType := range queues {
+		queueTypes[queueType] = true
+	}
+
+	for queueType := range queueTypes {
+		oldQueue := existing.Queues[queueType]
+		newQueue := queues[queueType]
+		if !queueChanged(oldQueue, newQueue) {
+			continue
+		}
+		messageBus.PublishQueue(QueueChangeMessage{
+			EntityID:   existing.EntityID,
+			EntityName: existing.Name,
+			ParkID:     existing.ParkID,
+			EntityType: existing.EntityType,
+			QueueType:  queueType,
+			OldQueue:   oldQueue,
+			NewQueue:   newQueue,
+			Timestamp:  now,
+		})
+	}
+}
@@ -2,20 +2,31 @@ package main
 
 import (
 	"log"
+	"os"
 	"runtime"
+	"slices"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(app *fiber.App, entityManager *EntityManager, wsClient *WebSocketClient) {
+func SetupRoutes(app *fiber.App, entityManager *EntityManager, parkManager *ParkManager, liveSource LiveDataSource, wsClient *WebSocketClient, restClient *RestClient, reconciler *Reconciler, pollingFallback *PollingFallback, templates *NotificationTemplates) {
 	// Health check
 	app.Get("/health", healthHandler)
 
 	// Entity routes
 	app.Get("/api/entities", getAllEntitiesHandler(entityManager))
 	app.Get("/api/entities/:id", getEntityByIDHandler(entityManager))
+	app.Get("/api/entities/:id/history", getEntityHistoryHandler)
+	app.Get("/api/entities/:id/stats", getEntityStatsHandler(entityManager))
+
+	// Park routes
+	app.Get("/api/parks", getParksHandler(entityManager, parkManager))
+	app.Get("/api/parks/:id", getParkByIDHandler(entityManager, parkManager))
+	app.Get("/api/parks/:id/entities", getParkEntitiesHandler(entityManager, parkManager))
+	app.Get("/api/parks/:id/stats", getParkStatsHandler(entityManager, parkManager))
+	app.Get("/api/parks/:id/schedule", getParkScheduleHandler(parkManager))
 
 	// Device routes
 	app.Post("/api/register-device", registerDeviceHandler)
@@ -23,13 +34,41 @@ func SetupRoutes(app *fiber.App, entityManager *EntityManager, wsClient *WebSock
 	app.Get("/api/devices/:token/exists", checkDeviceExistsHandler)
 	app.Delete("/api/devices/:token", deleteDeviceHandler)
 
+	// Subscription routes
+	app.Get("/api/devices/:token/subscriptions", getSubscriptionsHandler)
+	app.Post("/api/devices/:token/subscriptions", subscribeHandler)
+	app.Delete("/api/devices/:token/subscriptions/:id", unsubscribeHandler)
+
+	// Wait time alert routes
+	app.Get("/api/devices/:token/wait-alerts", getWaitTimeAlertsHandler)
+	app.Post("/api/devices/:token/wait-alerts", addWaitTimeAlertHandler)
+	app.Delete("/api/devices/:token/wait-alerts/:id", deleteWaitTimeAlertHandler)
+
+	// Boarding group alert routes
+	app.Get("/api/devices/:token/boarding-group-alerts", getBoardingGroupAlertsHandler)
+	app.Post("/api/devices/:token/boarding-group-alerts", addBoardingGroupAlertHandler)
+	app.Delete("/api/devices/:token/boarding-group-alerts/:id", deleteBoardingGroupAlertHandler)
+
 	// APNS Message tracking
 	app.Get("/api/apns-messages", getAPNSMessagesHandler)
 	app.Post("/api/apns-receipt", apnsReceiptHandler)
 	app.Get("/api/apns-receipts", getAPNSReceiptsHandler)
 
+	// Admin routes
+	admin := app.Group("/api/admin", adminAuthMiddleware)
+	admin.Get("/parks", getAdminParksHandler(parkManager))
+	admin.Post("/parks", addParkHandler(parkManager, wsClient, restClient, entityManager))
+	admin.Delete("/parks/:id", removeParkHandler(parkManager, wsClient, entityManager))
+	admin.Post("/parks/:id/enable", setParkEnabledHandler(parkManager, wsClient, restClient, entityManager, true))
+	admin.Post("/parks/:id/disable", setParkEnabledHandler(parkManager, wsClient, restClient, entityManager, false))
+	admin.Get("/dead-letters", getDeadLettersHandler)
+	admin.Post("/dead-letters/redrive", redriveAllDeadLettersHandler)
+	admin.Post("/dead-letters/:id/redrive", redriveDeadLetterHandler)
+	admin.Delete("/dead-letters/:id", deleteDeadLetterHandler)
+	admin.Post("/templates/reload", reloadTemplatesHandler(templates))
+
 	// Metrics
-	app.Get("/api/metrics", metricsHandler(entityManager, wsClient))
+	app.Get("/api/metrics", metricsHandler(entityManager, liveSource, wsClient, reconciler, pollingFallback))
 
 	// Test routes
 	app.Post("/api/test/status-change", testStatusChangeHandler)
@@ -37,6 +76,23 @@ func SetupRoutes(app *fiber.App, entityManager *EntityManager, wsClient *WebSock
 	app.Post("/api/test/device-token", testDeviceTokenHandler)
 }
 
+// adminAuthMiddleware requires the X-Admin-Key header to match ADMIN_API_KEY. The admin
+// API is disabled while ADMIN_API_KEY isn't set.
+func adminAuthMiddleware(c *fiber.Ctx) error {
+	adminKey := os.Getenv("ADMIN_API_KEY")
+	if adminKey == "" {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Admin API is disabled, ADMIN_API_KEY is not set",
+		})
+	}
+	if c.Get("X-Admin-Key") != adminKey {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid admin key",
+		})
+	}
+	return c.Next()
+}
+
 // healthHandler handles health check requests
 func healthHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
@@ -66,6 +122,223 @@ func getEntityByIDHandler(entityManager *EntityManager) fiber.Handler {
 	}
 }
 
+// getEntityHistoryHandler returns an entity's recorded status and wait time changes.
+// Supports optional from/to (RFC3339) range and limit/offset pagination query parameters.
+func getEntityHistoryHandler(c *fiber.Ctx) error {
+	entityID := c.Params("id")
+
+	var from, to time.Time
+	var err error
+	if fromParam := c.Query("from"); fromParam != "" {
+		if from, err = time.Parse(time.RFC3339, fromParam); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "from must be an RFC3339 timestamp",
+			})
+		}
+	}
+	if toParam := c.Query("to"); toParam != "" {
+		if to, err = time.Parse(time.RFC3339, toParam); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "to must be an RFC3339 timestamp",
+			})
+		}
+	}
+
+	limit := 100 // Default limit
+	if parsedLimit := c.QueryInt("limit", 100); parsedLimit > 0 && parsedLimit <= 1000 {
+		limit = parsedLimit
+	}
+
+	offset := 0
+	if parsedOffset := c.QueryInt("offset", 0); parsedOffset > 0 {
+		offset = parsedOffset
+	}
+
+	events, err := db.GetEntityEvents(entityID, from, to, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"entityId": entityID,
+		"events":   events,
+		"count":    len(events),
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// ParkSummary describes a configured park and the entities currently tracked in it
+type ParkSummary struct {
+	Park
+	ParkIDs     []string             `json:"parkIds"`
+	EntityCount int                  `json:"entityCount"`
+	Statuses    map[EntityStatus]int `json:"statuses"`
+}
+
+// summarizePark builds a ParkSummary from the entities in a park and its child parks
+func summarizePark(park Park, entityManager *EntityManager, parkManager *ParkManager) ParkSummary {
+	parkIDs := append([]string{park.ID}, parkManager.GetChildParkIDs(park.ID)...)
+	entities := entityManager.GetEntitiesByPark(parkIDs...)
+
+	summary := ParkSummary{
+		Park:        park,
+		ParkIDs:     parkIDs[1:],
+		EntityCount: len(entities),
+		Statuses:    make(map[EntityStatus]int),
+	}
+	for _, entity := range entities {
+		summary.Statuses[entity.Status]++
+	}
+	return summary
+}
+
+// getParksHandler returns every enabled park with entity counts and a status breakdown
+func getParksHandler(entityManager *EntityManager, parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parks := parkManager.GetEnabledParks()
+		summaries := make([]ParkSummary, 0, len(parks))
+		for _, park := range parks {
+			summaries = append(summaries, summarizePark(park, entityManager, parkManager))
+		}
+		return c.JSON(fiber.Map{
+			"parks": summaries,
+			"count": len(summaries),
+		})
+	}
+}
+
+// getParkByIDHandler returns a specific park with entity counts and a status breakdown
+func getParkByIDHandler(entityManager *EntityManager, parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		park, exists := parkManager.GetPark(c.Params("id"))
+		if !exists {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Park not found",
+			})
+		}
+		return c.JSON(summarizePark(park, entityManager, parkManager))
+	}
+}
+
+// getParkEntitiesHandler returns the entities in a park. The ID may be a configured
+// park (destination), which includes all of its child parks, or an individual park.
+func getParkEntitiesHandler(entityManager *EntityManager, parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parkID := c.Params("id")
+		parkIDs := append([]string{parkID}, parkManager.GetChildParkIDs(parkID)...)
+
+		entities := entityManager.GetEntitiesByPark(parkIDs...)
+		if entities == nil {
+			entities = []Entity{}
+		}
+		return c.JSON(fiber.Map{
+			"parkId":   parkID,
+			"entities": entities,
+			"count":    len(entities),
+		})
+	}
+}
+
+// getParkScheduleHandler returns the opening windows for a park, or for every park in a destination.
+// Supports optional from/to (YYYY-MM-DD) query parameters; from defaults to yesterday (UTC).
+func getParkScheduleHandler(parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parkID := c.Params("id")
+		parkIDs := append([]string{parkID}, parkManager.GetChildParkIDs(parkID)...)
+
+		fromDate := c.Query("from", time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02"))
+		toDate := c.Query("to")
+		for _, date := range []string{fromDate, toDate} {
+			if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "from and to must be YYYY-MM-DD dates",
+				})
+			}
+		}
+
+		schedule, err := db.GetParkSchedule(parkIDs, fromDate, toDate)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if schedule == nil {
+			schedule = []ScheduleEntry{}
+		}
+
+		timezone := ""
+		if park, ok := parkManager.ResolvePark(parkID); ok {
+			timezone = park.Timezone
+		}
+
+		return c.JSON(fiber.Map{
+			"parkId":   parkID,
+			"timezone": timezone,
+			"schedule": schedule,
+			"count":    len(schedule),
+		})
+	}
+}
+
+// getEntityStatsHandler returns downtime analytics for an entity over a day, week or month window
+func getEntityStatsHandler(entityManager *EntityManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entityID := c.Params("id")
+		from, to, err := parseStatsWindow(c.Query("window"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		stats, err := GetEntityDowntimeStats(entityManager, entityID, from, to)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"entityId": entityID,
+			"from":     from,
+			"to":       to,
+			"stats":    stats,
+		})
+	}
+}
+
+// getParkStatsHandler returns downtime analytics for a park and each of its entities. The ID may
+// be a configured park (destination), which includes all of its child parks, or an individual park.
+func getParkStatsHandler(entityManager *EntityManager, parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parkID := c.Params("id")
+		from, to, err := parseStatsWindow(c.Query("window"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		total, perEntity, err := GetParkDowntimeStats(entityManager, parkManager, parkID, from, to)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"parkId":   parkID,
+			"from":     from,
+			"to":       to,
+			"stats":    total,
+			"entities": perEntity,
+		})
+	}
+}
+
 // registerDeviceHandler handles device registration
 func registerDeviceHandler(c *fiber.Ctx) error {
 	var registration DeviceRegistration
@@ -80,8 +353,8 @@ func registerDeviceHandler(c *fiber.Ctx) error {
 		registration.Environment = "development"
 	}
 
-	log.Printf("Received device registration: DeviceToken=%s, AppVersion=%s, DeviceType=%s, Environment=%s, LastUpdated=%v",
-		registration.DeviceToken, registration.AppVersion, registration.DeviceType, registration.Environment, registration.LastUpdated)
+	log.Printf("Received device registration: DeviceToken=%s, AppVersion=%s, DeviceType=%s, Environment=%s, Alerts=%t, Locale=%s, LastUpdated=%v",
+		registration.DeviceToken, registration.AppVersion, registration.DeviceType, registration.Environment, registration.Alerts, registration.Locale, registration.LastUpdated)
 
 	if registration.DeviceToken == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -154,6 +427,355 @@ func deleteDeviceHandler(c *fiber.Ctx) error {
 	})
 }
 
+// getSubscriptionsHandler returns all subscriptions for a device
+func getSubscriptionsHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	subscriptions, err := db.GetSubscriptions(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"subscriptions": subscriptions,
+		"count":         len(subscriptions),
+	})
+}
+
+// subscribeHandler subscribes a device to status changes for an entity or a whole park
+func subscribeHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+
+	var subscriptionData struct {
+		EntityID    string             `json:"entityId"`
+		ParkID      string             `json:"parkId"`
+		EntityType  string             `json:"entityType"`
+		Statuses    []EntityStatus     `json:"statuses"`
+		Transitions []StatusTransition `json:"transitions"`
+		Queues      []string           `json:"queues"`
+	}
+
+	if err := c.BodyParser(&subscriptionData); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// A subscription targets exactly one entity or one park
+	if (subscriptionData.EntityID == "") == (subscriptionData.ParkID == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Exactly one of entity ID or park ID is required",
+		})
+	}
+
+	// Entity type only narrows park subscriptions, to a type that is ingested
+	if subscriptionData.EntityType != "" {
+		if subscriptionData.EntityID != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Entity type can only narrow a park subscription",
+			})
+		}
+		if !isTrackableEntityType(subscriptionData.EntityType) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unsupported entity type: " + subscriptionData.EntityType,
+			})
+		}
+	}
+
+	for _, status := range subscriptionData.Statuses {
+		if !isKnownStatus(status) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown status: " + string(status),
+			})
+		}
+	}
+
+	for _, transition := range subscriptionData.Transitions {
+		if transition.From == "" && transition.To == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Transition needs a from or to status",
+			})
+		}
+		for _, status := range []EntityStatus{transition.From, transition.To} {
+			if status != "" && !isKnownStatus(status) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Unknown status: " + string(status),
+				})
+			}
+		}
+	}
+
+	for _, queueType := range subscriptionData.Queues {
+		if !isKnownQueueType(queueType) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown queue type: " + queueType,
+			})
+		}
+	}
+
+	// Only registered devices can subscribe
+	device, err := db.GetDeviceToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if device == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Device not found",
+		})
+	}
+
+	subscription := Subscription{
+		DeviceToken: token,
+		EntityID:    subscriptionData.EntityID,
+		ParkID:      subscriptionData.ParkID,
+		EntityType:  subscriptionData.EntityType,
+		Statuses:    subscriptionData.Statuses,
+		Transitions: subscriptionData.Transitions,
+		Queues:      subscriptionData.Queues,
+	}
+
+	if err := db.AddSubscription(subscription); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Device %s subscribed to entity=%q park=%q type=%q statuses=%v transitions=%v queues=%v",
+		token, subscription.EntityID, subscription.ParkID, subscription.EntityType, subscription.Statuses, subscription.Transitions, subscription.Queues)
+
+	return c.JSON(fiber.Map{
+		"status":       "Subscribed successfully",
+		"subscription": subscription,
+	})
+}
+
+// unsubscribeHandler removes one of a device's subscriptions
+func unsubscribeHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid subscription ID",
+		})
+	}
+
+	if err := db.RemoveSubscription(token, int64(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Device %s removed subscription %d", token, id)
+
+	return c.JSON(fiber.Map{
+		"status": "Unsubscribed successfully",
+	})
+}
+
+// getWaitTimeAlertsHandler returns all wait time alerts for a device
+func getWaitTimeAlertsHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	alerts, err := db.GetWaitTimeAlerts(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// addWaitTimeAlertHandler registers a wait time threshold alert for a device
+func addWaitTimeAlertHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+
+	var alertData struct {
+		EntityID  string `json:"entityId"`
+		Threshold int    `json:"threshold"`
+	}
+
+	if err := c.BodyParser(&alertData); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if alertData.EntityID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Entity ID is required",
+		})
+	}
+
+	if alertData.Threshold <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Threshold must be a positive number of minutes",
+		})
+	}
+
+	// Only registered devices can add alerts
+	device, err := db.GetDeviceToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if device == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Device not found",
+		})
+	}
+
+	alert := WaitTimeAlert{
+		DeviceToken: token,
+		EntityID:    alertData.EntityID,
+		Threshold:   alertData.Threshold,
+	}
+
+	if err := db.AddWaitTimeAlert(alert); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Device %s added wait time alert for entity %s at %d minutes", token, alert.EntityID, alert.Threshold)
+
+	return c.JSON(fiber.Map{
+		"status": "Wait time alert added successfully",
+		"alert":  alert,
+	})
+}
+
+// deleteWaitTimeAlertHandler removes one of a device's wait time alerts
+func deleteWaitTimeAlertHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert ID",
+		})
+	}
+
+	if err := db.RemoveWaitTimeAlert(token, int64(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "Wait time alert deleted successfully",
+	})
+}
+
+// getBoardingGroupAlertsHandler returns all boarding group alerts for a device
+func getBoardingGroupAlertsHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	alerts, err := db.GetBoardingGroupAlerts(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"alerts": alerts,
+		"count":  len(alerts),
+	})
+}
+
+// addBoardingGroupAlertHandler registers a boarding group or virtual queue opening alert for a device
+func addBoardingGroupAlertHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+
+	var alertData struct {
+		EntityID   string `json:"entityId"`
+		GroupStart int    `json:"groupStart"`
+		GroupEnd   int    `json:"groupEnd"`
+	}
+
+	if err := c.BodyParser(&alertData); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if alertData.EntityID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Entity ID is required",
+		})
+	}
+
+	// A single group can be given as just groupStart
+	if alertData.GroupEnd == 0 {
+		alertData.GroupEnd = alertData.GroupStart
+	}
+
+	if alertData.GroupStart < 0 || alertData.GroupEnd < alertData.GroupStart || (alertData.GroupStart == 0 && alertData.GroupEnd != 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Boarding groups must be a positive range from groupStart to groupEnd",
+		})
+	}
+
+	// Only registered devices can add alerts
+	device, err := db.GetDeviceToken(token)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if device == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Device not found",
+		})
+	}
+
+	alert := BoardingGroupAlert{
+		DeviceToken: token,
+		EntityID:    alertData.EntityID,
+		GroupStart:  alertData.GroupStart,
+		GroupEnd:    alertData.GroupEnd,
+	}
+
+	if err := db.AddBoardingGroupAlert(alert); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	log.Printf("Device %s added boarding group alert for entity %s, groups %d-%d", token, alert.EntityID, alert.GroupStart, alert.GroupEnd)
+
+	return c.JSON(fiber.Map{
+		"status": "Boarding group alert added successfully",
+		"alert":  alert,
+	})
+}
+
+// deleteBoardingGroupAlertHandler removes one of a device's boarding group alerts
+func deleteBoardingGroupAlertHandler(c *fiber.Ctx) error {
+	token := c.Params("token")
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert ID",
+		})
+	}
+
+	if err := db.RemoveBoardingGroupAlert(token, int64(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "Boarding group alert deleted successfully",
+	})
+}
+
 // getAPNSMessagesHandler returns recent APNS messages for debugging
 func getAPNSMessagesHandler(c *fiber.Ctx) error {
 	limit := 100 // Default limit
@@ -261,8 +883,293 @@ func getAPNSReceiptsHandler(c *fiber.Ctx) error {
 	})
 }
 
+// getDeadLettersHandler returns the pushes that exhausted their retries, newest first
+func getDeadLettersHandler(c *fiber.Ctx) error {
+	limit := 100 // Default limit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit := c.QueryInt("limit", 100); parsedLimit > 0 && parsedLimit <= 1000 {
+			limit = parsedLimit
+		}
+	}
+
+	deadLetters, err := db.GetDeadLetters(limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"deadLetters": deadLetters,
+		"count":       len(deadLetters),
+		"limit":       limit,
+	})
+}
+
+// redriveDeadLetterHandler puts a dead letter back on the push queue
+func redriveDeadLetterHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dead letter ID",
+		})
+	}
+
+	deadLetter, err := db.GetDeadLetter(int64(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if deadLetter == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	}
+
+	if err := redriveDeadLetter(*deadLetter); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status":     "Dead letter re-driven successfully",
+		"deadLetter": deadLetter,
+	})
+}
+
+// redriveAllDeadLettersHandler puts up to limit dead letters back on the push queue, e.g. after an APNS outage
+func redriveAllDeadLettersHandler(c *fiber.Ctx) error {
+	limit := 100 // Default limit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit := c.QueryInt("limit", 100); parsedLimit > 0 && parsedLimit <= 1000 {
+			limit = parsedLimit
+		}
+	}
+
+	deadLetters, err := db.GetDeadLetters(limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	redriven := 0
+	for _, deadLetter := range deadLetters {
+		if err := redriveDeadLetter(deadLetter); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":    err.Error(),
+				"redriven": redriven,
+			})
+		}
+		redriven++
+	}
+
+	return c.JSON(fiber.Map{
+		"status":   "Dead letters re-driven successfully",
+		"redriven": redriven,
+	})
+}
+
+// redriveDeadLetter removes a dead letter and queues its push again. It is removed first so
+// a push that fails again is stored as a new dead letter rather than duplicating this one.
+func redriveDeadLetter(deadLetter DeadLetter) error {
+	if err := db.DeleteDeadLetter(deadLetter.ID); err != nil {
+		return err
+	}
+	log.Printf("Re-driving dead letter %d to %s after %d failed attempts (%s)", deadLetter.ID, deadLetter.Request.DeviceToken, deadLetter.Attempts, deadLetter.LastError)
+	Push(deadLetter.Request)
+	return nil
+}
+
+// deleteDeadLetterHandler discards a dead letter without re-driving it
+func deleteDeadLetterHandler(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dead letter ID",
+		})
+	}
+
+	if err := db.DeleteDeadLetter(int64(id)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "Dead letter deleted successfully",
+	})
+}
+
+// reloadTemplatesHandler reloads the notification templates file, keeping the current
+// templates if it can't be loaded
+func reloadTemplatesHandler(templates *NotificationTemplates) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := templates.Reload(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"status": "Notification templates reloaded successfully",
+		})
+	}
+}
+
+// getAdminParksHandler returns every configured park, enabled or not
+func getAdminParksHandler(parkManager *ParkManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parks := parkManager.GetParks()
+		return c.JSON(fiber.Map{
+			"parks": parks,
+			"count": len(parks),
+		})
+	}
+}
+
+// addParkHandler adds or updates a park and starts ingesting it if enabled
+func addParkHandler(parkManager *ParkManager, wsClient *WebSocketClient, restClient *RestClient, entityManager *EntityManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		park := Park{Enabled: true}
+		if err := c.BodyParser(&park); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		if park.ID == "" || park.Name == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Park ID and name are required",
+			})
+		}
+
+		for _, entityType := range park.EntityTypes {
+			if !isTrackableEntityType(entityType) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Unsupported entity type: " + entityType,
+				})
+			}
+		}
+
+		existing, existed := parkManager.GetPark(park.ID)
+		park, err := parkManager.AddPark(park)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		switch {
+		case park.Enabled && !(existed && existing.Enabled):
+			startParkIngestion(park, wsClient, restClient, entityManager)
+		case !park.Enabled && existed && existing.Enabled:
+			wsClient.UnsubscribePark(park)
+			evictParkEntities(park, parkManager.GetChildParkIDs(park.ID), entityManager)
+		case park.Enabled && !slices.Equal(park.TrackedEntityTypes(), existing.TrackedEntityTypes()):
+			// Resubscribe with the new entity type filter, drop the entities it no longer
+			// tracks and load the newly tracked ones
+			wsClient.UnsubscribePark(existing)
+			if removed := entityManager.RemoveUntrackedEntities(park, parkManager.GetChildParkIDs(park.ID)); removed > 0 {
+				log.Printf("Removed %d entities park %s (%s) no longer tracks", removed, park.Name, park.ID)
+			}
+			startParkIngestion(park, wsClient, restClient, entityManager)
+		}
+
+		log.Printf("Park %s (%s) added, enabled=%t", park.Name, park.ID, park.Enabled)
+
+		return c.JSON(fiber.Map{
+			"status": "Park saved successfully",
+			"park":   park,
+		})
+	}
+}
+
+// removeParkHandler deletes a park and stops ingesting it
+func removeParkHandler(parkManager *ParkManager, wsClient *WebSocketClient, entityManager *EntityManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		park, exists := parkManager.GetPark(c.Params("id"))
+		if !exists {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Park not found",
+			})
+		}
+
+		// Removing the park forgets which parks belong to it
+		childParkIDs := parkManager.GetChildParkIDs(park.ID)
+		if err := parkManager.RemovePark(park.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if park.Enabled {
+			wsClient.UnsubscribePark(park)
+		}
+		evictParkEntities(park, childParkIDs, entityManager)
+
+		log.Printf("Park %s (%s) removed", park.Name, park.ID)
+
+		return c.JSON(fiber.Map{
+			"status": "Park removed successfully",
+		})
+	}
+}
+
+// setParkEnabledHandler enables or disables ingestion for a park
+func setParkEnabledHandler(parkManager *ParkManager, wsClient *WebSocketClient, restClient *RestClient, entityManager *EntityManager, enabled bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		existing, exists := parkManager.GetPark(c.Params("id"))
+		if !exists {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Park not found",
+			})
+		}
+
+		park, err := parkManager.SetParkEnabled(existing.ID, enabled)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if enabled && !existing.Enabled {
+			startParkIngestion(park, wsClient, restClient, entityManager)
+		} else if !enabled && existing.Enabled {
+			wsClient.UnsubscribePark(park)
+			evictParkEntities(park, parkManager.GetChildParkIDs(park.ID), entityManager)
+		}
+
+		log.Printf("Park %s (%s) enabled=%t", park.Name, park.ID, park.Enabled)
+
+		return c.JSON(fiber.Map{
+			"status": "Park updated successfully",
+			"park":   park,
+		})
+	}
+}
+
+// startParkIngestion pre-populates a newly enabled park and subscribes to its live feed
+func startParkIngestion(park Park, wsClient *WebSocketClient, restClient *RestClient, entityManager *EntityManager) {
+	go func() {
+		if _, err := restClient.PrePopulatePark(park, entityManager); err != nil {
+			log.Printf("Error fetching entities for park %s: %v", park.Name, err)
+		}
+	}()
+	wsClient.SubscribePark(park)
+}
+
+// evictParkEntities drops the entities of a park that is no longer ingested, and of the parks
+// that belong to it, so they aren't served or counted as if they were still live
+func evictParkEntities(park Park, childParkIDs []string, entityManager *EntityManager) {
+	removed := entityManager.RemoveEntitiesByPark(append([]string{park.ID}, childParkIDs...)...)
+	log.Printf("Removed %d entities of park %s (%s)", removed, park.Name, park.ID)
+}
+
 // metricsHandler returns server metrics
-func metricsHandler(entityManager *EntityManager, wsClient *WebSocketClient) fiber.Handler {
+func metricsHandler(entityManager *EntityManager, liveSource LiveDataSource, wsClient *WebSocketClient, reconciler *Reconciler, pollingFallback *PollingFallback) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get device count
 		devices, err := db.GetAllDevices()
@@ -289,13 +1196,20 @@ func metricsHandler(entityManager *EntityManager, wsClient *WebSocketClient) fib
 
 		return c.JSON(fiber.Map{
 			"queue_length":   len(EntityQueue),
+			"push_queue":     pushQueue.GetStats(),
+			"push_outbox":    pushOutbox.GetStats(),
 			"entity_count":   len(entityManager.GetAllEntities()),
 			"entity_stats":   entityStats,
 			"device_count":   deviceCount,
 			"goroutines":     runtime.NumGoroutine(),
+			"source":         liveSource.Name(),
 			"restarts":       GetReconnectionTimestamps(),
+			"breaker":        wsClient.GetBreakerStats(),
+			"activity":       wsClient.GetActivityStats(),
 			"events":         wsClient.GetEventStats(),
 			"statuses":       wsClient.GetStatusStats(),
+			"reconciler":     reconciler.GetStats(),
+			"ingestion":      pollingFallback.GetStats(),
 			"server_start":   serverStartTime,
 		})
 	}
@@ -304,11 +1218,12 @@ func metricsHandler(entityManager *EntityManager, wsClient *WebSocketClient) fib
 // testStatusChangeHandler simulates a status change
 func testStatusChangeHandler(c *fiber.Ctx) error {
 	msg := StatusChangeMessage{
-		EntityID:  "f0d4b531-e291-471b-9527-00410c2bbd65",
-		ParkID:    "ca888437-ebb4-4d50-aed2-d227f7096968",
-		OldStatus: "DOWN",
-		NewStatus: "OPERATING",
-		Timestamp: time.Now(),
+		EntityID:   "f0d4b531-e291-471b-9527-00410c2bbd65",
+		ParkID:     "ca888437-ebb4-4d50-aed2-d227f7096968",
+		EntityType: "ATTRACTION",
+		OldStatus:  "DOWN",
+		NewStatus:  "OPERATING",
+		Timestamp:  time.Now(),
 	}
 
 	messageBus.PublishStatus(msg)
@@ -323,10 +1238,11 @@ func testStatusChangeHandler(c *fiber.Ctx) error {
 // testStatusChangeCustomHandler simulates a custom status change
 func testStatusChangeCustomHandler(c *fiber.Ctx) error {
 	var testData struct {
-		EntityID  string `json:"entityId"`
-		ParkID    string `json:"parkId"`
-		OldStatus string `json:"oldStatus"`
-		NewStatus string `json:"newStatus"`
+		EntityID   string `json:"entityId"`
+		ParkID     string `json:"parkId"`
+		EntityType string `json:"entityType"`
+		OldStatus  string `json:"oldStatus"`
+		NewStatus  string `json:"newStatus"`
 	}
 
 	if err := c.BodyParser(&testData); err != nil {
@@ -336,11 +1252,12 @@ func testStatusChangeCustomHandler(c *fiber.Ctx) error {
 	}
 
 	msg := StatusChangeMessage{
-		EntityID:  testData.EntityID,
-		ParkID:    testData.ParkID,
-		OldStatus: EntityStatus(testData.OldStatus),
-		NewStatus: EntityStatus(testData.NewStatus),
-		Timestamp: time.Now(),
+		EntityID:   testData.EntityID,
+		ParkID:     testData.ParkID,
+		EntityType: testData.EntityType,
+		OldStatus:  EntityStatus(testData.OldStatus),
+		NewStatus:  EntityStatus(testData.NewStatus),
+		Timestamp:  time.Now(),
 	}
 
 	messageBus.PublishStatus(msg)
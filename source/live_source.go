@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// LiveDataSource is a provider of live entity data. Sources emit updates into the
+// entity queue with QueueEntity, so EntityManager doesn't depend on where they came from.
+type LiveDataSource interface {
+	// Name identifies the source in logs and metrics
+	Name() string
+	// Start emits updates until the source is closed. It blocks, so call it in a goroutine.
+	Start()
+	// Close stops the source
+	Close()
+}
+
+// Live data sources selectable with LIVE_DATA_SOURCE
+const (
+	SourceWebSocket = "websocket"
+	SourceREST      = "rest"
+	SourceFile      = "file"
+)
+
+// maxFileLineSize bounds a single line read by the file source
+const maxFileLineSize = 1024 * 1024
+
+// FileSource replays entity updates from a newline-delimited JSON file, one Entity per
+// line in the same format as GET /api/entities, queueing one update every interval
+type FileSource struct {
+	path     string
+	interval time.Duration
+	done     chan struct{}
+}
+
+// NewFileSource creates a source that replays the entities in path
+func NewFileSource(path string, interval time.Duration) *FileSource {
+	return &FileSource{
+		path:     path,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+// Name identifies the source in logs and metrics
+func (f *FileSource) Name() string {
+	return SourceFile
+}
+
+// Start queues every entity in the file and returns once the file is exhausted or the source is closed
+func (f *FileSource) Start() {
+	if err := f.replay(); err != nil {
+		log.Printf("File source: %v", err)
+	}
+}
+
+// replay reads the file and queues its entities
+func (f *FileSource) replay() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", f.path, err)
+	}
+	defer file.Close()
+
+	log.Printf("Replaying entities from %s (every %v)", f.path, f.interval)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxFileLineSize)
+
+	queued := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entity Entity
+		if err := json.Unmarshal(scanner.Bytes(), &entity); err != nil {
+			log.Printf("File source: skipping line %d: %v", line, err)
+			continue
+		}
+
+		QueueEntity(entity)
+		queued++
+
+		select {
+		case <-f.done:
+			log.Printf("File source closed after %d entities", queued)
+			return nil
+		case <-time.After(f.interval):
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %v", f.path, err)
+	}
+
+	log.Printf("File source finished, queued %d entities", queued)
+	return nil
+}
+
+// Close stops the replay
+func (f *FileSource) Close() {
+	close(f.done)
+}
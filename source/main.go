@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
@@ -38,6 +39,20 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return value
 }
 
+// getEnvIntWithDefault returns the environment variable parsed as an integer, or the default value if not set or invalid
+func getEnvIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
 // AddReconnectionTimestamp adds a new reconnection timestamp to the global array
 func AddReconnectionTimestamp() {
 	reconnectionMutex.Lock()
@@ -104,25 +119,78 @@ func main() {
 		log.Fatal("Failed to initialize APNS:", err)
 	}
 
-	// Get WebSocket URL and API key from environment variables
+	// Get WebSocket URL, REST URL and API key from environment variables
 	websocketURL := getEnvWithDefault("WEBSOCKET_URL", "wss://api.themeparks.wiki/v1/entity/live")
+	restURL := getEnvWithDefault("REST_URL", "https://api.themeparks.wiki/v1/entity")
 	apiKey := getEnvOrExit("THEMEPARK_API_KEY")
 
 	// Initialize entity manager
 	entityManager := NewEntityManager()
 
+	// Load the configured parks
+	parkManager, err := NewParkManager()
+	if err != nil {
+		log.Fatal("Failed to load parks:", err)
+	}
+
+	// Choose where live data comes from: the WebSocket feed, REST polling, or a recorded file
+	sourceName := getEnvWithDefault("LIVE_DATA_SOURCE", SourceWebSocket)
+	recorded := sourceName == SourceFile || sourceName == SourceReplay
+	pollInterval := time.Duration(getEnvIntWithDefault("POLL_INTERVAL_SECONDS", 30)) * time.Second
+
 	// Initialize REST client for pre-population
-	restClient := NewRestClient(apiKey)
+	restClient := NewRestClient(restURL, apiKey, parkManager, pollInterval)
 
-	// Pre-populate entities from REST API
-	log.Printf("Pre-populating entities from REST API...")
-	if err := restClient.PrePopulateEntities(entityManager); err != nil {
-		log.Printf("Warning: Failed to pre-populate entities: %v", err)
-	} else {
-		entityCount := restClient.GetEntityCount(entityManager)
-		log.Printf("Successfully pre-populated %d entities", entityCount)
+	// Pre-populate entities from REST API, unless we're replaying recorded data
+	if !recorded {
+		log.Printf("Pre-populating entities from REST API...")
+		if err := restClient.PrePopulateEntities(entityManager); err != nil {
+			log.Printf("Warning: Failed to pre-populate entities: %v", err)
+		} else {
+			entityCount := restClient.GetEntityCount(entityManager)
+			log.Printf("Successfully pre-populated %d entities", entityCount)
+		}
 	}
 
+	// Fetch park schedules now and once a day
+	restClient.StartScheduleFetcher(24 * time.Hour)
+
+	// Create the push queue between fan-out and the APNS workers. Fan-out never blocks on it;
+	// when it is full the overflow policy drops the oldest push, coalesces pushes per device
+	// or spills pushes to disk.
+	pushOutbox = NewPushOutbox(time.Duration(getEnvIntWithDefault("PUSH_LEASE_SECONDS", 300))*time.Second,
+		time.Duration(getEnvIntWithDefault("PUSH_OUTBOX_SWEEP_SECONDS", 30))*time.Second)
+	pushQueue, err = NewPushQueue(PushQueueConfig{
+		Capacity: getEnvIntWithDefault("PUSH_QUEUE_CAPACITY", 1000),
+		Overflow: getEnvWithDefault("PUSH_QUEUE_OVERFLOW", OverflowDropOldest),
+		SpillDir: os.Getenv("PUSH_QUEUE_SPILL_DIR"),
+		// A dropped or coalesced push must not come back from the outbox
+		Dropped: pushOutbox.Ack,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize push queue:", err)
+	}
+
+	// Resume pushes left in the outbox by the previous run, and re-queue pushes whose lease expires
+	go pushOutbox.Start()
+
+	// Load the notification templates, built in or overridden by NOTIFICATION_TEMPLATES_FILE
+	templates, err := NewNotificationTemplates(os.Getenv("NOTIFICATION_TEMPLATES_FILE"))
+	if err != nil {
+		log.Fatal("Failed to load notification templates:", err)
+	}
+
+	// Start message processors, subscribed to the message bus before anything publishes to it
+	StartMessageProcessors(parkManager, NewAlertBuilder(parkManager, templates))
+
+	// Start the APNS worker pool. Pushes failing with transient APNS errors are retried
+	// with backoff, then stored as dead letters.
+	StartAPNSWorkers(getEnvIntWithDefault("APNS_WORKERS", 5), PushRetryConfig{
+		MaxAttempts:  getEnvIntWithDefault("APNS_MAX_ATTEMPTS", 5),
+		InitialDelay: time.Duration(getEnvIntWithDefault("APNS_RETRY_INITIAL_MS", 500)) * time.Millisecond,
+		MaxDelay:     time.Duration(getEnvIntWithDefault("APNS_RETRY_MAX_SECONDS", 30)) * time.Second,
+	})
+
 	// Start entity processing worker
 	go func() {
 		for entity := range EntityQueue {
@@ -130,23 +198,67 @@ func main() {
 		}
 	}()
 
+	// Periodically re-poll the REST API to correct any drift from missed WebSocket updates
+	reconciler := NewReconciler(restClient, entityManager, time.Duration(getEnvIntWithDefault("RECONCILE_INTERVAL_SECONDS", 300))*time.Second)
+	if !recorded {
+		go reconciler.Start()
+	}
+
 	// Initialize WebSocket client
-	wsClient := NewWebSocketClient(websocketURL, apiKey)
+	wsClient := NewWebSocketClient(websocketURL, apiKey, parkManager, reconciler, RetryConfig{
+		InitialDelay:     time.Duration(getEnvIntWithDefault("WS_BACKOFF_INITIAL_MS", 1000)) * time.Millisecond,
+		MaxDelay:         time.Duration(getEnvIntWithDefault("WS_BACKOFF_MAX_SECONDS", 300)) * time.Second,
+		BreakerThreshold: getEnvIntWithDefault("WS_BREAKER_THRESHOLD", 10),
+		BreakerCooldown:  time.Duration(getEnvIntWithDefault("WS_BREAKER_COOLDOWN_SECONDS", 900)) * time.Second,
+	}, KeepaliveConfig{
+		PingInterval: time.Duration(getEnvIntWithDefault("WS_PING_INTERVAL_SECONDS", 30)) * time.Second,
+		StaleTimeout: time.Duration(getEnvIntWithDefault("WS_STALE_TIMEOUT_SECONDS", 120)) * time.Second,
+	})
+
+	// Fall back to REST polling while the WebSocket is down
+	pollingFallback := NewPollingFallback(wsClient, restClient,
+		time.Duration(getEnvIntWithDefault("WS_FALLBACK_AFTER_SECONDS", 60))*time.Second,
+		pollInterval)
 
-	// Start WebSocket client
-	go wsClient.Connect()
+	// Optionally record every raw WebSocket frame for replaying later
+	var recorder *FrameRecorder
+	if dir := os.Getenv("RECORD_FRAMES_DIR"); dir != "" {
+		recorder, err = NewFrameRecorder(dir, int64(getEnvIntWithDefault("RECORD_MAX_FILE_MB", 100))*1024*1024)
+		if err != nil {
+			log.Fatal("Failed to initialize frame recorder:", err)
+		}
+		wsClient.SetRecorder(recorder)
+	}
 
-	// Start message processors
-	StartMessageProcessors()
+	var liveSource LiveDataSource
+	switch sourceName {
+	case SourceWebSocket:
+		liveSource = wsClient
+		go pollingFallback.Start()
+	case SourceREST:
+		liveSource = restClient
+	case SourceFile:
+		liveSource = NewFileSource(getEnvOrExit("LIVE_DATA_FILE"),
+			time.Duration(getEnvIntWithDefault("LIVE_DATA_FILE_INTERVAL_MS", 100))*time.Millisecond)
+	case SourceReplay:
+		liveSource = NewReplaySource(getEnvOrExit("REPLAY_FILE"), getEnvIntWithDefault("REPLAY_SPEED", 1), wsClient)
+	default:
+		log.Fatalf("Unknown LIVE_DATA_SOURCE %q", sourceName)
+	}
 
-	// Start the APNS worker pool
-	StartAPNSWorkers(5) // Start 5 workers
+	// Start the live data source
+	log.Printf("Using %s live data source", liveSource.Name())
+	go liveSource.Start()
 
 	// Create Fiber app
 	app := fiber.New()
 
+	if os.Getenv("ADMIN_API_KEY") == "" {
+		log.Printf("Warning: ADMIN_API_KEY is not set, the admin API is disabled")
+	}
+
 	// Setup all routes using the handlers.go file
-	SetupRoutes(app, entityManager, wsClient)
+	SetupRoutes(app, entityManager, parkManager, liveSource, wsClient, restClient, reconciler, pollingFallback, templates)
 
 	// Start the server in a goroutine so it doesn't block
 	go func() {
@@ -162,6 +274,11 @@ func main() {
 	<-sigChan
 
 	// Cleanup
-	wsClient.Close()
+	liveSource.Close()
+	pushOutbox.Close()
+	pushQueue.Close()
+	if recorder != nil {
+		recorder.Close()
+	}
 	log.Println("Shutting down...")
 }
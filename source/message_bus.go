@@ -9,25 +9,45 @@ import (
 // Message types
 type StatusChangeMessage struct {
     EntityID      string
+    EntityName    string
     ParkID        string
+    EntityType    string
     OldStatus     EntityStatus
     NewStatus     EntityStatus
     OldWaitTime   int
     NewWaitTime   int
+    Scheduled     bool // a CLOSED transition at the entity's scheduled closing time
     Timestamp     time.Time
 }
 
 type WaitTimeMessage struct {
     EntityID      string
+    EntityName    string
+    ParkID        string
+    Status        EntityStatus
     OldWaitTime   int
     NewWaitTime   int
     Timestamp     time.Time
 }
 
-// MessageBus handles pub/sub for both status and wait time messages
+// QueueChangeMessage reports a return time or boarding group queue changing state,
+// or a boarding group queue calling different groups
+type QueueChangeMessage struct {
+    EntityID      string
+    EntityName    string
+    ParkID        string
+    EntityType    string
+    QueueType     string
+    OldQueue      QueueData
+    NewQueue      QueueData
+    Timestamp     time.Time
+}
+
+// MessageBus handles pub/sub for status, wait time and queue messages
 type MessageBus struct {
     statusSubscribers    []chan StatusChangeMessage
     waitTimeSubscribers  []chan WaitTimeMessage
+    queueSubscribers     []chan QueueChangeMessage
     mu                  sync.RWMutex
 }
 
@@ -40,6 +60,7 @@ func NewMessageBus() *MessageBus {
     return &MessageBus{
         statusSubscribers:   make([]chan StatusChangeMessage, 0),
         waitTimeSubscribers: make([]chan WaitTimeMessage, 0),
+        queueSubscribers:    make([]chan QueueChangeMessage, 0),
     }
 }
 
@@ -63,6 +84,16 @@ func (mb *MessageBus) SubscribeWaitTime() chan WaitTimeMessage {
     return ch
 }
 
+// Subscribe to queue changes
+func (mb *MessageBus) SubscribeQueue() chan QueueChangeMessage {
+    mb.mu.Lock()
+    defer mb.mu.Unlock()
+    
+    ch := make(chan QueueChangeMessage, 100)
+    mb.queueSubscribers = append(mb.queueSubscribers, ch)
+    return ch
+}
+
 // Publish status change
 func (mb *MessageBus) PublishStatus(msg StatusChangeMessage) {
     mb.mu.RLock()
@@ -91,4 +122,19 @@ func (mb *MessageBus) PublishWaitTime(msg WaitTimeMessage) {
             log.Printf("Wait time subscriber channel full, dropping message for entity %s", msg.EntityID)
         }
     }
-} 
\ No newline at end of file
+}
+
+// Publish queue change
+func (mb *MessageBus) PublishQueue(msg QueueChangeMessage) {
+    mb.mu.RLock()
+    defer mb.mu.RUnlock()
+    
+    for _, ch := range mb.queueSubscribers {
+        select {
+        case ch <- msg:
+            // Message sent successfully
+        default:
+            log.Printf("Queue subscriber channel full, dropping message for entity %s", msg.EntityID)
+        }
+    }
+}
@@ -1,41 +1,47 @@
 package main
 
 import (
-	"fmt"
 	"log"
 )
 
 // StartMessageProcessors subscribes to the message bus and processes incoming messages.
-func StartMessageProcessors() {
+// It subscribes before returning, so it must be called before any live data source starts
+// publishing or those messages are missed. Park subscriptions are matched through parkManager, so a subscription to a configured park
+// covers its child parks. Devices that want visible alerts get the text built by alertBuilder.
+func StartMessageProcessors(parkManager *ParkManager, alertBuilder *AlertBuilder) {
 	log.Printf("Starting message processors...")
 
 	// Goroutine for handling status changes (Fan-Out Processor)
+	statusCh := messageBus.SubscribeStatus()
 	go func() {
-		statusCh := messageBus.SubscribeStatus()
 		for msg := range statusCh {
 			log.Printf("🔔 STATUS CHANGE: Entity %s changed from %s to %s", msg.EntityID, msg.OldStatus, msg.NewStatus)
 
-			// 1. Get all registered devices.
-			// In a future state, this would get devices subscribed to this specific entity.
-			devices, err := db.GetAllDevices()
+			// Users only want to hear about unexpected closures
+			if msg.Scheduled {
+				log.Printf("FAN-OUT: Skipping scheduled closure for entity %s", msg.EntityID)
+				continue
+			}
+
+			// 1. Get the devices subscribed to this entity or its park.
+			devices, err := subscribedDevices(msg, parkManager.WithParentID(msg.ParkID))
 			if err != nil {
 				log.Printf("Error getting devices for fan-out: %v", err)
 				continue
 			}
 
 			if len(devices) == 0 {
-				log.Printf("FAN-OUT: No devices found for entity %s", msg.EntityID)
+				log.Printf("FAN-OUT: No subscribed devices found for entity %s", msg.EntityID)
 				continue
 			}
 
 			log.Printf("FAN-OUT: Found %d devices. Enqueuing APNs jobs...", len(devices))
 
 			// 2. Create and enqueue a push notification for each device.
-			notificationMsg := fmt.Sprintf("%s: %s -> %s", msg.EntityID, msg.OldStatus, msg.NewStatus)
 			for _, device := range devices {
 				pushReq := PushRequest{
 					DeviceToken: device.DeviceToken,
-					Message:     notificationMsg,
+					EventType:   EventStatusChange,
 					EntityID:    msg.EntityID,
 					ParkID:      msg.ParkID,
 					OldStatus:   string(msg.OldStatus),
@@ -44,19 +50,284 @@ func StartMessageProcessors() {
 					NewWaitTime: msg.NewWaitTime,
 					Environment: device.Environment,
 				}
+				alertBuilder.StatusChange(msg, device.Locale).Apply(&pushReq, device)
 				// Use the non-blocking Push function
 				Push(pushReq)
 			}
 		}
 	}()
 
-	// Goroutine for handling wait time changes
-	// TODO: Re-enable when working on wait time functionality
-	// go func() {
-	// 	waitTimeCh := messageBus.SubscribeWaitTime()
-	// 	for msg := range waitTimeCh {
-	// 		log.Printf("⏰ WAIT TIME CHANGE: Entity %s changed from %d to %d minutes at %v",
-	// 			msg.EntityID, msg.OldWaitTime, msg.NewWaitTime, msg.Timestamp)
-	// 	}
-	// }()
-} 
\ No newline at end of file
+	// Goroutine for handling wait time changes (Threshold Alert Processor)
+	waitTimeCh := messageBus.SubscribeWaitTime()
+	go func() {
+		for msg := range waitTimeCh {
+			log.Printf("⏰ WAIT TIME CHANGE: Entity %s changed from %d to %d minutes at %v",
+				msg.EntityID, msg.OldWaitTime, msg.NewWaitTime, msg.Timestamp)
+			processWaitTimeAlerts(msg, alertBuilder)
+		}
+	}()
+
+	// Goroutine for handling queue changes (Queue Opening Processor)
+	queueCh := messageBus.SubscribeQueue()
+	go func() {
+		for msg := range queueCh {
+			log.Printf("🎟️ QUEUE CHANGE: Entity %s %s changed from %q to %q",
+				msg.EntityID, msg.QueueType, msg.OldQueue.Availability(), msg.NewQueue.Availability())
+			processQueueOpened(msg, parkManager.WithParentID(msg.ParkID), alertBuilder)
+			if msg.QueueType == QueueBoardingGroup {
+				processBoardingGroupAlerts(msg, alertBuilder)
+			}
+		}
+	}()
+}
+
+// processQueueOpened notifies the devices subscribed to a queue type when that queue
+// becomes available, e.g. return times being offered or boarding groups opening.
+// parkIDs are the entity's park and the configured park it belongs to.
+func processQueueOpened(msg QueueChangeMessage, parkIDs []string, alertBuilder *AlertBuilder) {
+	if msg.NewQueue.Availability() != QueueAvailable || msg.OldQueue.Availability() == QueueAvailable {
+		return
+	}
+
+	devices, err := devicesForSubscriptions(msg.EntityID, parkIDs, func(subscription Subscription) bool {
+		return subscriptionTargets(subscription, msg.EntityID, parkIDs, msg.EntityType) &&
+			containsString(subscription.Queues, msg.QueueType)
+	})
+	if err != nil {
+		log.Printf("Error getting devices for queue fan-out: %v", err)
+		return
+	}
+
+	for _, device := range devices {
+		pushReq := PushRequest{
+			DeviceToken: device.DeviceToken,
+			EventType:   EventQueueOpened,
+			EntityID:    msg.EntityID,
+			ParkID:      msg.ParkID,
+			OldStatus:   msg.OldQueue.Availability(),
+			NewStatus:   msg.NewQueue.Availability(),
+			QueueType:   msg.QueueType,
+			Environment: device.Environment,
+		}
+		alertBuilder.QueueOpened(msg, device.Locale).Apply(&pushReq, device)
+		Push(pushReq)
+	}
+}
+
+// processBoardingGroupAlerts fires the alerts whose boarding groups have been called, or
+// whose virtual queue has opened. Like wait time alerts, an alert fires once and re-arms when
+// its condition no longer holds, e.g. when the groups called reset for the next day.
+func processBoardingGroupAlerts(msg QueueChangeMessage, alertBuilder *AlertBuilder) {
+	alerts, err := db.GetBoardingGroupAlertsForEntity(msg.EntityID)
+	if err != nil {
+		log.Printf("Error getting boarding group alerts for entity %s: %v", msg.EntityID, err)
+		return
+	}
+
+	for _, alert := range alerts {
+		called, known := boardingGroupCalled(alert, msg.NewQueue)
+		if alert.Triggered {
+			if known && !called {
+				if err := db.SetBoardingGroupAlertTriggered(alert.ID, false); err != nil {
+					log.Printf("Error re-arming boarding group alert %d: %v", alert.ID, err)
+				}
+			}
+			continue
+		}
+
+		if !called {
+			continue
+		}
+
+		device, err := db.GetDeviceToken(alert.DeviceToken)
+		if err != nil {
+			log.Printf("Error getting device %s for boarding group alert: %v", alert.DeviceToken, err)
+			continue
+		}
+		if device == nil {
+			continue
+		}
+
+		if alert.GroupStart == 0 {
+			log.Printf("BOARDING GROUP ALERT: %s virtual queue open, notifying %s", msg.EntityID, alert.DeviceToken)
+		} else {
+			log.Printf("BOARDING GROUP ALERT: %s boarding groups %d-%d called (alert for %d-%d), notifying %s", msg.EntityID,
+				*msg.NewQueue.CurrentGroupStart, *msg.NewQueue.CurrentGroupEnd, alert.GroupStart, alert.GroupEnd, alert.DeviceToken)
+		}
+
+		if err := db.SetBoardingGroupAlertTriggered(alert.ID, true); err != nil {
+			log.Printf("Error marking boarding group alert %d as triggered: %v", alert.ID, err)
+			continue
+		}
+
+		pushReq := PushRequest{
+			DeviceToken: device.DeviceToken,
+			EventType:   EventBoardingGroup,
+			EntityID:    msg.EntityID,
+			ParkID:      msg.ParkID,
+			OldStatus:   msg.OldQueue.Availability(),
+			NewStatus:   msg.NewQueue.Availability(),
+			QueueType:   msg.QueueType,
+			Environment: device.Environment,
+		}
+		alertBuilder.BoardingGroup(msg, alert, device.Locale).Apply(&pushReq, *device)
+		Push(pushReq)
+	}
+}
+
+// boardingGroupCalled reports whether a boarding group queue satisfies an alert, and whether
+// the queue says enough to tell. Group alerts are satisfied once the groups called reach the
+// alert's first group; open alerts while the queue is available.
+func boardingGroupCalled(alert BoardingGroupAlert, queue QueueData) (called bool, known bool) {
+	if alert.GroupStart == 0 {
+		return queue.Availability() == QueueAvailable, true
+	}
+	if queue.CurrentGroupStart == nil || queue.CurrentGroupEnd == nil {
+		// Paused or closed queues don't report groups, which says nothing about the alert
+		return false, false
+	}
+	return *queue.CurrentGroupEnd >= alert.GroupStart, true
+}
+
+// processWaitTimeAlerts fires the alerts whose threshold the new wait time has dropped to.
+// An alert stays quiet after firing until the wait goes back above its threshold, so a wait
+// bouncing around the threshold doesn't notify on every update.
+func processWaitTimeAlerts(msg WaitTimeMessage, alertBuilder *AlertBuilder) {
+	// Closed or down entities report no standby wait, which would look like a zero minute wait
+	if msg.Status != StatusOperating {
+		return
+	}
+
+	alerts, err := db.GetWaitTimeAlertsForEntity(msg.EntityID)
+	if err != nil {
+		log.Printf("Error getting wait time alerts for entity %s: %v", msg.EntityID, err)
+		return
+	}
+
+	for _, alert := range alerts {
+		if alert.Triggered {
+			if msg.NewWaitTime > alert.Threshold {
+				if err := db.SetWaitTimeAlertTriggered(alert.ID, false); err != nil {
+					log.Printf("Error re-arming wait time alert %d: %v", alert.ID, err)
+				}
+			}
+			continue
+		}
+
+		if msg.NewWaitTime > alert.Threshold {
+			continue
+		}
+
+		device, err := db.GetDeviceToken(alert.DeviceToken)
+		if err != nil {
+			log.Printf("Error getting device %s for wait time alert: %v", alert.DeviceToken, err)
+			continue
+		}
+		if device == nil {
+			continue
+		}
+
+		log.Printf("WAIT ALERT: Entity %s wait %d <= %d, notifying %s", msg.EntityID, msg.NewWaitTime, alert.Threshold, alert.DeviceToken)
+
+		if err := db.SetWaitTimeAlertTriggered(alert.ID, true); err != nil {
+			log.Printf("Error marking wait time alert %d as triggered: %v", alert.ID, err)
+			continue
+		}
+
+		pushReq := PushRequest{
+			DeviceToken: device.DeviceToken,
+			EventType:   EventWaitTime,
+			EntityID:    msg.EntityID,
+			ParkID:      msg.ParkID,
+			OldStatus:   string(msg.Status),
+			NewStatus:   string(msg.Status),
+			OldWaitTime: msg.OldWaitTime,
+			NewWaitTime: msg.NewWaitTime,
+			Environment: device.Environment,
+		}
+		alertBuilder.WaitTime(msg, device.Locale).Apply(&pushReq, *device)
+		Push(pushReq)
+	}
+}
+
+// subscribedDevices returns each device with at least one subscription matching the status change
+func subscribedDevices(msg StatusChangeMessage, parkIDs []string) ([]DeviceRegistration, error) {
+	return devicesForSubscriptions(msg.EntityID, parkIDs, func(subscription Subscription) bool {
+		return subscriptionMatches(subscription, msg, parkIDs)
+	})
+}
+
+// devicesForSubscriptions returns each device with at least one subscription to the entity
+// or one of its parks that matches. A device following both the entity and its park is only
+// returned once.
+func devicesForSubscriptions(entityID string, parkIDs []string, matches func(Subscription) bool) ([]DeviceRegistration, error) {
+	subscribed, err := db.GetSubscribedDevices(entityID, parkIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var devices []DeviceRegistration
+	for _, sd := range subscribed {
+		if seen[sd.Device.DeviceToken] || !matches(sd.Subscription) {
+			continue
+		}
+		seen[sd.Device.DeviceToken] = true
+		devices = append(devices, sd.Device)
+	}
+
+	return devices, nil
+}
+
+// subscriptionTargets reports whether a subscription covers an entity in one of parkIDs
+func subscriptionTargets(subscription Subscription, entityID string, parkIDs []string, entityType string) bool {
+	if subscription.EntityID != "" && subscription.EntityID != entityID {
+		return false
+	}
+	if subscription.ParkID != "" && !containsString(parkIDs, subscription.ParkID) {
+		return false
+	}
+	if subscription.EntityType != "" && subscription.EntityType != entityType {
+		return false
+	}
+	return true
+}
+
+// subscriptionMatches reports whether a subscription's filters accept a status change
+func subscriptionMatches(subscription Subscription, msg StatusChangeMessage, parkIDs []string) bool {
+	if !subscriptionTargets(subscription, msg.EntityID, parkIDs, msg.EntityType) {
+		return false
+	}
+	if len(subscription.Statuses) > 0 && !containsStatus(subscription.Statuses, msg.NewStatus) {
+		return false
+	}
+	if len(subscription.Transitions) == 0 {
+		return true
+	}
+	for _, transition := range subscription.Transitions {
+		if transition.Matches(msg.OldStatus, msg.NewStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsStatus reports whether status is in statuses
+func containsStatus(statuses []EntityStatus, status EntityStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether value is in values
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
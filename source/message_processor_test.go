@@ -0,0 +1,150 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDevicesForSubscriptions(t *testing.T) {
+	useTestDB(t)
+
+	for _, device := range []DeviceRegistration{
+		{DeviceToken: "entity-and-park", Environment: "production", Alerts: true, Locale: "ja-JP"},
+		{DeviceToken: "park", Environment: "development"},
+		{DeviceToken: "other-park", Environment: "production"},
+	} {
+		if err := db.StoreDeviceToken(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, subscription := range []Subscription{
+		{DeviceToken: "entity-and-park", EntityID: "ride"},
+		{DeviceToken: "entity-and-park", ParkID: testParkID},
+		{DeviceToken: "park", ParkID: testDestinationID},
+		{DeviceToken: "other-park", ParkID: testOtherParkID},
+		{DeviceToken: "unregistered", EntityID: "ride"},
+	} {
+		if err := db.AddSubscription(subscription); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	devices, err := devicesForSubscriptions("ride", []string{testParkID, testDestinationID}, func(Subscription) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each subscribed device comes back once with its registration, unregistered devices not at all
+	got := make(map[string]DeviceRegistration)
+	for _, device := range devices {
+		if _, ok := got[device.DeviceToken]; ok {
+			t.Errorf("device %s returned twice", device.DeviceToken)
+		}
+		got[device.DeviceToken] = device
+	}
+	if len(got) != 2 {
+		t.Fatalf("got devices %v, want entity-and-park and park", got)
+	}
+	if device := got["entity-and-park"]; !device.Alerts || device.Locale != "ja-JP" || device.Environment != "production" {
+		t.Errorf("entity-and-park = %+v, want its stored registration", device)
+	}
+	if device := got["park"]; device.Environment != "development" {
+		t.Errorf("park = %+v, want its stored registration", device)
+	}
+}
+
+// boardingGroups is a boarding group queue calling groups start to end
+func boardingGroups(start, end int) QueueData {
+	return QueueData{AllocationStatus: QueueAvailable, CurrentGroupStart: &start, CurrentGroupEnd: &end}
+}
+
+func TestBoardingGroupCalled(t *testing.T) {
+	groupAlert := BoardingGroupAlert{GroupStart: 40, GroupEnd: 45}
+	openAlert := BoardingGroupAlert{}
+
+	tests := []struct {
+		name   string
+		alert  BoardingGroupAlert
+		queue  QueueData
+		called bool
+		known  bool
+	}{
+		{"groups not reached yet", groupAlert, boardingGroups(1, 39), false, true},
+		{"first group reached", groupAlert, boardingGroups(21, 40), true, true},
+		{"groups called past the alert", groupAlert, boardingGroups(46, 60), true, true},
+		{"paused queue reports no groups", groupAlert, QueueData{AllocationStatus: "PAUSED"}, false, false},
+		{"open alert on an open queue", openAlert, QueueData{AllocationStatus: QueueAvailable}, true, true},
+		{"open alert on a paused queue", openAlert, QueueData{AllocationStatus: "PAUSED"}, false, true},
+		{"open alert on a closed queue", openAlert, QueueData{AllocationStatus: "CLOSED"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if called, known := boardingGroupCalled(tt.alert, tt.queue); called != tt.called || known != tt.known {
+				t.Errorf("boardingGroupCalled = %v, %v, want %v, %v", called, known, tt.called, tt.known)
+			}
+		})
+	}
+}
+
+func TestProcessBoardingGroupAlertsHysteresis(t *testing.T) {
+	outbox := newOutboxTest(t, 10)
+	previous := pushOutbox
+	pushOutbox = outbox
+	t.Cleanup(func() { pushOutbox = previous })
+
+	parkManager, err := NewParkManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := NewNotificationTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertBuilder := NewAlertBuilder(parkManager, templates)
+
+	if err := db.StoreDeviceToken(DeviceRegistration{DeviceToken: "device", Environment: "production"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddBoardingGroupAlert(BoardingGroupAlert{DeviceToken: "device", EntityID: "ride", GroupStart: 40, GroupEnd: 45}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each step is applied in order to the same alert
+	steps := []struct {
+		name      string
+		queue     QueueData
+		pushed    bool
+		triggered bool
+	}{
+		{"groups not reached yet", boardingGroups(1, 20), false, false},
+		{"alert's group called", boardingGroups(21, 40), true, true},
+		{"later groups don't notify again", boardingGroups(41, 60), false, true},
+		{"pausing keeps the alert triggered", QueueData{AllocationStatus: "PAUSED"}, false, true},
+		{"groups resuming past the alert stay quiet", boardingGroups(61, 70), false, true},
+		{"next day's groups re-arm the alert", boardingGroups(1, 10), false, false},
+		{"alert's group called again", boardingGroups(11, 45), true, true},
+	}
+	queue := QueueData{}
+	for _, step := range steps {
+		unwritten := outbox.GetStats()["unwritten"].(int)
+		processBoardingGroupAlerts(QueueChangeMessage{
+			EntityID:  "ride",
+			QueueType: QueueBoardingGroup,
+			OldQueue:  queue,
+			NewQueue:  step.queue,
+			Timestamp: time.Now(),
+		}, alertBuilder)
+		queue = step.queue
+
+		if pushed := outbox.GetStats()["unwritten"].(int) > unwritten; pushed != step.pushed {
+			t.Errorf("%s: pushed = %v, want %v", step.name, pushed, step.pushed)
+		}
+		alerts, err := db.GetBoardingGroupAlertsForEntity("ride")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 1 || alerts[0].Triggered != step.triggered {
+			t.Fatalf("%s: alerts = %+v, want one with triggered = %v", step.name, alerts, step.triggered)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// outboxSweepBatch is the most expired pushes re-queued by one sweep
+const outboxSweepBatch = 1000
+
+// PushOutbox keeps every push in SQLite until it has been sent, so pushes pending at a
+// deploy or crash are resumed on the next start.
+//
+// Fan-out only hands pushes to the outbox; its writer stores whatever has accumulated in one
+// transaction and then puts those pushes on the push queue, so a status change fanning out to
+// many devices doesn't wait on a disk write per device.
+//
+// A push is leased while it waits in the push queue and while a worker sends it. Workers
+// take a new lease before every attempt and remove the push once it has been sent or given
+// up on, and both only succeed for the holder of the latest lease. Pushes the queue's
+// overflow policy drops are removed too. Pushes whose lease runs out, e.g. because a worker
+// stopped mid-send, are swept back into the queue under a new lease, so a stale copy still in
+// the queue can never be sent as well.
+type PushOutbox struct {
+	lease         time.Duration
+	sweepInterval time.Duration
+	started       time.Time
+	wake          chan struct{}
+	done          chan struct{}
+	stopped       chan struct{}
+
+	mu         sync.Mutex
+	unwritten  []PushRequest
+	added      int
+	acked      int
+	requeued   int
+	lostLeases int
+	lastSweep  time.Time
+}
+
+// NewPushOutbox creates an outbox whose leases last for lease, sweeping for expired
+// leases every sweepInterval
+func NewPushOutbox(lease time.Duration, sweepInterval time.Duration) *PushOutbox {
+	return &PushOutbox{
+		lease:         lease,
+		sweepInterval: sweepInterval,
+		started:       time.Now(),
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+// Add hands a push to the outbox without blocking. It is queued once the writer has stored it.
+func (o *PushOutbox) Add(req PushRequest) {
+	o.mu.Lock()
+	o.unwritten = append(o.unwritten, req)
+	o.mu.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+		// The writer is already due to run
+	}
+}
+
+// write stores the pushes added since the last write and queues them. If they can't be
+// stored they are queued anyway and are only held in memory.
+func (o *PushOutbox) write() {
+	o.mu.Lock()
+	pushes := o.unwritten
+	o.unwritten = nil
+	o.mu.Unlock()
+	if len(pushes) == 0 {
+		return
+	}
+
+	stored, err := db.AddOutboxPushes(pushes, time.Now().Add(o.lease))
+	if err != nil {
+		log.Printf("Outbox: %v, queueing %d pushes in memory only", err, len(pushes))
+		stored = pushes
+	} else {
+		o.mu.Lock()
+		o.added += len(stored)
+		o.mu.Unlock()
+	}
+
+	for _, req := range stored {
+		pushQueue.Enqueue(req)
+	}
+}
+
+// Renew takes a new lease on a push before an attempt to send it. It returns false if the
+// push was leased again after this copy was queued, or has already been acknowledged, in
+// which case this copy must not be sent.
+func (o *PushOutbox) Renew(req *PushRequest) bool {
+	if req.OutboxID == 0 {
+		return true
+	}
+
+	leased, err := db.LeaseOutboxPush(req.OutboxID, req.LeaseID, time.Now().Add(o.lease))
+	if err != nil {
+		// Sending without the lease risks a duplicate, but not sending risks losing the push
+		log.Printf("Outbox: %v, sending push %d anyway", err, req.OutboxID)
+		return true
+	}
+	if !leased {
+		o.mu.Lock()
+		o.lostLeases++
+		o.mu.Unlock()
+		return false
+	}
+
+	req.LeaseID++
+	return true
+}
+
+// Ack removes a push that has been sent, dead-lettered or dropped from the outbox
+func (o *PushOutbox) Ack(req PushRequest) {
+	if req.OutboxID == 0 {
+		return
+	}
+
+	if err := db.DeleteOutboxPush(req.OutboxID, req.LeaseID); err != nil {
+		log.Printf("Outbox: %v", err)
+		return
+	}
+
+	o.mu.Lock()
+	o.acked++
+	o.mu.Unlock()
+}
+
+// Start resumes the pushes left by the previous run, then writes added pushes as they arrive
+// and re-queues pushes whose lease has expired every sweep interval, until the outbox is closed
+func (o *PushOutbox) Start() {
+	defer close(o.stopped)
+
+	// Every lease held by the previous run has been abandoned, even those that haven't expired.
+	// Pushes added by this run are leased past this horizon, so they aren't resumed twice.
+	if resumed := o.sweep(o.started.Add(o.lease)); resumed > 0 {
+		log.Printf("Outbox: resumed %d pushes from the previous run", resumed)
+	}
+
+	ticker := time.NewTicker(o.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.done:
+			// Store what is left so it is resumed on the next start
+			o.write()
+			return
+		case <-o.wake:
+			o.write()
+		case <-ticker.C:
+			if requeued := o.sweep(time.Now()); requeued > 0 {
+				log.Printf("Outbox: re-queued %d pushes with expired leases", requeued)
+			}
+		}
+	}
+}
+
+// sweep re-leases the pushes whose lease ended before the given time and puts them back
+// on the push queue, returning how many were re-queued
+func (o *PushOutbox) sweep(before time.Time) int {
+	pushes, err := db.GetExpiredOutboxPushes(before, outboxSweepBatch)
+	if err != nil {
+		log.Printf("Outbox: %v", err)
+		return 0
+	}
+
+	requeued := 0
+	for _, req := range pushes {
+		if !o.Renew(&req) {
+			continue
+		}
+		pushQueue.Enqueue(req)
+		requeued++
+	}
+
+	o.mu.Lock()
+	o.requeued += requeued
+	o.lastSweep = time.Now()
+	o.mu.Unlock()
+	return requeued
+}
+
+// Close stops the writer and sweeping once the pushes already added have been stored.
+// Pushes still in the outbox are resumed on the next start.
+func (o *PushOutbox) Close() {
+	close(o.done)
+	<-o.stopped
+}
+
+// GetStats returns the number of pushes waiting in the outbox and what has happened to them
+func (o *PushOutbox) GetStats() map[string]interface{} {
+	pending, err := db.CountOutboxPushes()
+	if err != nil {
+		log.Printf("Outbox: %v", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return map[string]interface{}{
+		"pending":       pending,
+		"unwritten":     len(o.unwritten),
+		"added":         o.added,
+		"acked":         o.acked,
+		"requeued":      o.requeued,
+		"lost_leases":   o.lostLeases,
+		"lease_seconds": o.lease.Seconds(),
+		"last_sweep":    o.lastSweep,
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newOutboxTest creates an outbox and a push queue of the given capacity that acks dropped
+// pushes through it, on a fresh database
+func newOutboxTest(t *testing.T, capacity int) *PushOutbox {
+	t.Helper()
+	useTestDB(t)
+
+	outbox := NewPushOutbox(time.Minute, time.Hour)
+	queue, err := NewPushQueue(PushQueueConfig{
+		Capacity: capacity,
+		Overflow: OverflowDropOldest,
+		Dropped:  outbox.Ack,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := pushQueue
+	pushQueue = queue
+	t.Cleanup(func() {
+		queue.Close()
+		pushQueue = previous
+	})
+	return outbox
+}
+
+// dequeue takes the next push off the queue, failing the test if none arrives
+func dequeue(t *testing.T) PushRequest {
+	t.Helper()
+	got := make(chan PushRequest, 1)
+	go func() {
+		if req, ok := pushQueue.Dequeue(); ok {
+			got <- req
+		}
+	}()
+	select {
+	case req := <-got:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no push was queued")
+		return PushRequest{}
+	}
+}
+
+func countOutbox(t *testing.T) int {
+	t.Helper()
+	count, err := db.CountOutboxPushes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// storeOutboxPush stores a push as if it had been added by a previous run, leased until leaseUntil
+func storeOutboxPush(t *testing.T, token string, leaseUntil time.Time) PushRequest {
+	t.Helper()
+	stored, err := db.AddOutboxPushes([]PushRequest{{DeviceToken: token, EventType: EventStatusChange}}, leaseUntil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored[0]
+}
+
+func TestPushOutboxStoresThenQueues(t *testing.T) {
+	outbox := newOutboxTest(t, 10)
+	go outbox.Start()
+	defer outbox.Close()
+
+	for _, token := range []string{"a", "b", "c"} {
+		outbox.Add(PushRequest{DeviceToken: token, EventType: EventStatusChange})
+	}
+
+	seen := make(map[int64]bool)
+	for _, want := range []string{"a", "b", "c"} {
+		req := dequeue(t)
+		if req.DeviceToken != want {
+			t.Errorf("dequeued push to %s, want %s", req.DeviceToken, want)
+		}
+		if req.OutboxID == 0 || req.LeaseID != 1 || seen[req.OutboxID] {
+			t.Errorf("push to %s has outbox ID %d lease %d, want a new row under lease 1", req.DeviceToken, req.OutboxID, req.LeaseID)
+		}
+		seen[req.OutboxID] = true
+	}
+	if count := countOutbox(t); count != 3 {
+		t.Errorf("outbox holds %d pushes, want 3 until they are acked", count)
+	}
+}
+
+func TestPushOutboxLeases(t *testing.T) {
+	outbox := newOutboxTest(t, 10)
+	queued := storeOutboxPush(t, "a", time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name    string
+		req     PushRequest
+		renewed bool
+		leaseID int64
+	}{
+		{"latest lease renews", queued, true, 2},
+		{"stale copy loses its lease", queued, false, 1},
+		{"push outside the outbox always sends", PushRequest{DeviceToken: "b"}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			if renewed := outbox.Renew(&req); renewed != tt.renewed || req.LeaseID != tt.leaseID {
+				t.Errorf("Renew = %v with lease %d, want %v with lease %d", renewed, req.LeaseID, tt.renewed, tt.leaseID)
+			}
+		})
+	}
+	if lost := outbox.GetStats()["lost_leases"]; lost != 1 {
+		t.Errorf("lost_leases = %v, want 1", lost)
+	}
+
+	// Only the holder of the latest lease can ack
+	outbox.Ack(queued)
+	if count := countOutbox(t); count != 1 {
+		t.Fatalf("stale ack removed the push")
+	}
+	current := queued
+	current.LeaseID = 2
+	outbox.Ack(current)
+	if count := countOutbox(t); count != 0 {
+		t.Fatalf("outbox holds %d pushes after the ack, want 0", count)
+	}
+	if outbox.Renew(&current) {
+		t.Error("acked push was renewed")
+	}
+}
+
+func TestPushOutboxSweep(t *testing.T) {
+	outbox := newOutboxTest(t, 10)
+	expired := storeOutboxPush(t, "expired", time.Now().Add(-time.Second))
+	storeOutboxPush(t, "leased", time.Now().Add(time.Minute))
+
+	if requeued := outbox.sweep(time.Now()); requeued != 1 {
+		t.Fatalf("sweep re-queued %d pushes, want only the expired one", requeued)
+	}
+	req := dequeue(t)
+	if req.OutboxID != expired.OutboxID || req.LeaseID != 2 {
+		t.Errorf("re-queued %+v, want push %d under lease 2", req, expired.OutboxID)
+	}
+
+	// The copy queued before the sweep can no longer be sent
+	if outbox.Renew(&expired) {
+		t.Error("stale copy renewed after the sweep")
+	}
+}
+
+func TestPushOutboxStartupSweep(t *testing.T) {
+	useTestDB(t)
+
+	// Pushes left by the previous run, whether or not their lease has expired
+	previous := []PushRequest{
+		storeOutboxPush(t, "expired", time.Now().Add(-time.Second)),
+		storeOutboxPush(t, "unexpired", time.Now().Add(time.Minute)),
+	}
+
+	outbox := NewPushOutbox(time.Minute, time.Hour)
+	queue, err := NewPushQueue(PushQueueConfig{Capacity: 10, Overflow: OverflowDropOldest, Dropped: outbox.Ack})
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := pushQueue
+	pushQueue = queue
+	t.Cleanup(func() {
+		queue.Close()
+		pushQueue = saved
+	})
+
+	// A push this run stored just before the sweep is already queued and mustn't be resumed
+	storeOutboxPush(t, "current", time.Now().Add(time.Minute))
+
+	go outbox.Start()
+	defer outbox.Close()
+
+	for _, want := range previous {
+		req := dequeue(t)
+		if req.OutboxID != want.OutboxID || req.LeaseID != 2 {
+			t.Errorf("resumed %+v, want push %d under lease 2", req, want.OutboxID)
+		}
+	}
+	if depth := queue.GetStats()["depth"]; depth != 0 {
+		t.Errorf("queue depth = %v after resuming, want 0", depth)
+	}
+}
+
+func TestPushOutboxAcksDroppedPushes(t *testing.T) {
+	outbox := newOutboxTest(t, 1)
+	go outbox.Start()
+
+	outbox.Add(PushRequest{DeviceToken: "old"})
+	outbox.Add(PushRequest{DeviceToken: "new"})
+	outbox.Close() // writes everything added
+
+	if count := countOutbox(t); count != 1 {
+		t.Errorf("outbox holds %d pushes, want only the one still queued", count)
+	}
+	if req := dequeue(t); req.DeviceToken != "new" {
+		t.Errorf("queued push to %s, want the newest", req.DeviceToken)
+	}
+
+	// Once the lease expires only the undelivered push comes back, not the dropped one
+	if requeued := outbox.sweep(time.Now().Add(2 * time.Minute)); requeued != 1 {
+		t.Errorf("sweep re-queued %d pushes, want only the undelivered one", requeued)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+type ParkType string
+
+const (
+	Disney    ParkType = "disney"
+	Universal ParkType = "universal"
+)
+
+type Park struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Type        ParkType `json:"type"`
+	Timezone    string   `json:"timezone,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	EntityTypes []string `json:"entityTypes,omitempty"` // entity types to ingest, defaultEntityTypes if empty
+}
+
+// Entity types that can be ingested
+const (
+	EntityTypeAttraction = "ATTRACTION"
+	EntityTypeShow       = "SHOW"
+	EntityTypeRestaurant = "RESTAURANT"
+)
+
+// defaultEntityTypes are ingested for parks that don't configure their own
+var defaultEntityTypes = []string{EntityTypeAttraction}
+
+// isTrackableEntityType reports whether entities of a type can be ingested
+func isTrackableEntityType(entityType string) bool {
+	switch entityType {
+	case EntityTypeAttraction, EntityTypeShow, EntityTypeRestaurant:
+		return true
+	}
+	return false
+}
+
+// TrackedEntityTypes returns the entity types ingested for the park
+func (p Park) TrackedEntityTypes() []string {
+	if len(p.EntityTypes) == 0 {
+		return defaultEntityTypes
+	}
+	return p.EntityTypes
+}
+
+// Tracks reports whether entities of a type are ingested for the park
+func (p Park) Tracks(entityType string) bool {
+	for _, tracked := range p.TrackedEntityTypes() {
+		if tracked == entityType {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultParks seeds the parks table the first time the server starts
+var defaultParks = []Park{
+	// Disney Parks
+	{ID: "bfc89fd6-314d-44b4-b89e-df1a89cf991e", Name: "Disneyland Resort", Type: Disney, Enabled: true},
+	{ID: "e957da41-3552-4cf6-b636-5babc5cbc4e5", Name: "Walt Disney World® Resort", Type: Disney, Enabled: true},
+	{ID: "abcfffe7-01f2-4f92-ae61-5093346f5a68", Name: "Hong Kong Disneyland Parks", Type: Disney, Enabled: true},
+	{ID: "faff60df-c766-4470-8adb-dee78e813f42", Name: "Tokyo Disney Resort", Type: Disney, Enabled: true},
+	{ID: "6e1464ca-1e9b-49c3-8937-c5c6f6675057", Name: "Shanghai Disney Resort", Type: Disney, Enabled: true},
+	{ID: "e8d0207f-da8a-4048-bec8-117aa946b2c2", Name: "Disneyland Paris", Type: Disney, Enabled: true},
+
+	// Universal Parks
+	{ID: "9fc68f1c-3f5e-4f09-89f2-aab2cf1a0741", Name: "Universal Studios", Type: Universal, Enabled: true},
+	{ID: "89db5d43-c434-4097-b71f-f6869f495a22", Name: "Universal Orlando Resort", Type: Universal, Enabled: true},
+}
+
+// ParkManager holds the configured parks, backed by the parks table.
+// Configured parks are usually destinations (resorts); the individual parks inside
+// them, which entities report as their ParkID, are learned from the REST API.
+type ParkManager struct {
+	parks      []Park
+	childParks map[string]string // child park ID -> configured park ID
+	mu         sync.RWMutex
+}
+
+// NewParkManager loads the configured parks from the database, seeding the defaults if none exist
+func NewParkManager() (*ParkManager, error) {
+	parks, err := db.GetParks()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parks) == 0 {
+		log.Printf("No parks configured, seeding %d default parks", len(defaultParks))
+		for _, park := range defaultParks {
+			if err := db.StorePark(park); err != nil {
+				return nil, err
+			}
+		}
+		parks = append([]Park(nil), defaultParks...)
+	}
+
+	return &ParkManager{
+		parks:      parks,
+		childParks: make(map[string]string),
+	}, nil
+}
+
+// GetParks returns a copy of every configured park
+func (pm *ParkManager) GetParks() []Park {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	parks := make([]Park, len(pm.parks))
+	copy(parks, pm.parks)
+	return parks
+}
+
+// GetEnabledParks returns the parks that should be ingested
+func (pm *ParkManager) GetEnabledParks() []Park {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var parks []Park
+	for _, park := range pm.parks {
+		if park.Enabled {
+			parks = append(parks, park)
+		}
+	}
+	return parks
+}
+
+// GetPark retrieves a park by its ID
+func (pm *ParkManager) GetPark(parkID string) (Park, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, park := range pm.parks {
+		if park.ID == parkID {
+			return park, true
+		}
+	}
+	return Park{}, false
+}
+
+// GetParkName returns the name of a configured park, or of the configured park
+// a child park belongs to, or "Unknown"
+func (pm *ParkManager) GetParkName(parkID string) string {
+	if park, ok := pm.ResolvePark(parkID); ok {
+		return park.Name
+	}
+	return "Unknown"
+}
+
+// AddPark adds a park or updates an existing one, returning the park as stored
+func (pm *ParkManager) AddPark(park Park) (Park, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	index := -1
+	for i, existing := range pm.parks {
+		if existing.ID == park.ID {
+			index = i
+			// The timezone comes from the API, so keep it when the update doesn't set one
+			if park.Timezone == "" {
+				park.Timezone = existing.Timezone
+			}
+			// Keep the tracked entity types unless the update changes them
+			if park.EntityTypes == nil {
+				park.EntityTypes = existing.EntityTypes
+			}
+			break
+		}
+	}
+
+	if err := db.StorePark(park); err != nil {
+		return Park{}, err
+	}
+
+	if index >= 0 {
+		pm.parks[index] = park
+	} else {
+		pm.parks = append(pm.parks, park)
+	}
+	return park, nil
+}
+
+// RemovePark deletes a park and forgets the child parks that belonged to it
+func (pm *ParkManager) RemovePark(parkID string) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if err := db.DeletePark(parkID); err != nil {
+		return err
+	}
+
+	for i, existing := range pm.parks {
+		if existing.ID == parkID {
+			pm.parks = append(pm.parks[:i], pm.parks[i+1:]...)
+			break
+		}
+	}
+	for childID, parentID := range pm.childParks {
+		if parentID == parkID {
+			delete(pm.childParks, childID)
+		}
+	}
+	return nil
+}
+
+// SetParkEnabled enables or disables ingestion for a park
+func (pm *ParkManager) SetParkEnabled(parkID string, enabled bool) (Park, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for i, existing := range pm.parks {
+		if existing.ID == parkID {
+			existing.Enabled = enabled
+			if err := db.StorePark(existing); err != nil {
+				return Park{}, err
+			}
+			pm.parks[i] = existing
+			return existing, nil
+		}
+	}
+	return Park{}, fmt.Errorf("park %s not found", parkID)
+}
+
+// SetParkTimezone records the timezone reported by the API for a configured park
+func (pm *ParkManager) SetParkTimezone(parkID string, timezone string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for i, existing := range pm.parks {
+		if existing.ID == parkID && existing.Timezone != timezone {
+			existing.Timezone = timezone
+			if err := db.StorePark(existing); err != nil {
+				log.Printf("Failed to store timezone for park %s: %v", parkID, err)
+				return
+			}
+			pm.parks[i] = existing
+			return
+		}
+	}
+}
+
+// RegisterChildPark records that an individual park belongs to a configured park
+func (pm *ParkManager) RegisterChildPark(parentID string, childID string) {
+	if childID == "" || childID == parentID {
+		return
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.childParks[childID] = parentID
+}
+
+// GetChildParkIDs returns the individual parks known to belong to a configured park
+func (pm *ParkManager) GetChildParkIDs(parentID string) []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var childIDs []string
+	for childID, id := range pm.childParks {
+		if id == parentID {
+			childIDs = append(childIDs, childID)
+		}
+	}
+	sort.Strings(childIDs)
+	return childIDs
+}
+
+// TracksEntityType reports whether entities of a type are ingested for a configured park or
+// one of its child parks. Parks that aren't configured track the default entity types.
+func (pm *ParkManager) TracksEntityType(parkID string, entityType string) bool {
+	park, _ := pm.ResolvePark(parkID)
+	return park.Tracks(entityType)
+}
+
+// WithParentID returns a park ID together with the ID of the configured park it belongs
+// to, so things attached to either one can be found. Configured and unknown parks only
+// return their own ID.
+func (pm *ParkManager) WithParentID(parkID string) []string {
+	park, ok := pm.ResolvePark(parkID)
+	if !ok || park.ID == parkID {
+		return []string{parkID}
+	}
+	return []string{parkID, park.ID}
+}
+
+// ResolvePark returns the configured park for a park ID, which may be the
+// configured park itself or one of its child parks
+func (pm *ParkManager) ResolvePark(parkID string) (Park, bool) {
+	if park, ok := pm.GetPark(parkID); ok {
+		return park, true
+	}
+
+	pm.mu.RLock()
+	parentID, ok := pm.childParks[parkID]
+	pm.mu.RUnlock()
+	if !ok {
+		return Park{}, false
+	}
+	return pm.GetPark(parentID)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Ingestion modes reported in /api/metrics
+const (
+	IngestionWebSocket = "websocket"
+	IngestionPolling   = "polling"
+)
+
+// PollingFallback switches ingestion to REST polling when the WebSocket feed has been
+// down for longer than a grace period, and back to the WebSocket once it reconnects.
+// Polled entities go through QueueEntity exactly like WebSocket updates.
+type PollingFallback struct {
+	wsClient   *WebSocketClient
+	restClient *RestClient
+	after      time.Duration
+	interval   time.Duration
+
+	mu        sync.RWMutex
+	mode      string
+	modeSince time.Time
+	polls     uint64
+}
+
+// NewPollingFallback creates a fallback that starts polling every interval once the
+// WebSocket has been disconnected for longer than after
+func NewPollingFallback(wsClient *WebSocketClient, restClient *RestClient, after time.Duration, interval time.Duration) *PollingFallback {
+	return &PollingFallback{
+		wsClient:   wsClient,
+		restClient: restClient,
+		after:      after,
+		interval:   interval,
+		mode:       IngestionWebSocket,
+		modeSince:  time.Now(),
+	}
+}
+
+// Start checks the WebSocket on every interval and polls while it is down. It blocks, so call it in a goroutine.
+func (p *PollingFallback) Start() {
+	log.Printf("Starting polling fallback (after %v down, every %v)", p.after, p.interval)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if p.wsClient.IsConnected() || time.Since(p.wsClient.DisconnectedSince()) < p.after {
+			p.setMode(IngestionWebSocket)
+			continue
+		}
+
+		p.setMode(IngestionPolling)
+		p.poll()
+	}
+}
+
+// poll fetches every enabled park once and queues its entities
+func (p *PollingFallback) poll() {
+	queued := p.restClient.PollEntities()
+
+	p.mu.Lock()
+	p.polls++
+	p.mu.Unlock()
+
+	log.Printf("Polling: queued %d entities", queued)
+}
+
+// setMode records a change of ingestion mode
+func (p *PollingFallback) setMode(mode string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.mode == mode {
+		return
+	}
+	log.Printf("Ingestion mode changed from %s to %s", p.mode, mode)
+	p.mode = mode
+	p.modeSince = time.Now()
+}
+
+// GetStats returns the current ingestion mode for the metrics endpoint
+func (p *PollingFallback) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return map[string]interface{}{
+		"mode":       p.mode,
+		"mode_since": p.modeSince,
+		"polls":      p.polls,
+	}
+}
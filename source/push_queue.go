@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Push queue overflow policies, deciding what gives way when a push arrives at a full queue
+const (
+	OverflowDropOldest = "drop-oldest" // drop the push that has waited longest
+	OverflowCoalesce   = "coalesce"    // replace the device's pending push, or drop the oldest if it has none
+	OverflowSpill      = "spill"       // write pushes to a file until the queue drains
+)
+
+// pushSpillFile is the file in the spill directory that holds spilled pushes, one JSON PushRequest per line
+const pushSpillFile = "push-spill.ndjson"
+
+// PushQueueConfig controls the size of the push queue and what happens when it is full
+type PushQueueConfig struct {
+	Capacity int
+	Overflow string
+	SpillDir string // directory for the spill file; defaults to the data directory
+
+	// Dropped, if set, is called with each push the overflow policy drops or replaces with a
+	// newer push to the same device
+	Dropped func(PushRequest)
+}
+
+// PushQueue is a bounded queue of push notifications between fan-out and the APNS workers.
+// Enqueue never blocks, so a slow APNS backend can't stall fan-out; the overflow policy
+// decides what happens to pushes arriving while the queue is full.
+type PushQueue struct {
+	config PushQueueConfig
+
+	mu      sync.Mutex
+	ready   *sync.Cond
+	pending []PushRequest
+	closed  bool
+
+	// Spill file, written through spillWriter and read back in order through spillReader
+	spillWriter *os.File
+	spillFile   *os.File
+	spillReader *bufio.Reader
+	spillDepth  int
+
+	highWater int
+	enqueued  int
+	dropped   int
+	coalesced int
+	spilled   int
+}
+
+// NewPushQueue creates a push queue. With the spill policy, pushes left in the spill file
+// by a previous run are delivered once the queue drains.
+func NewPushQueue(config PushQueueConfig) (*PushQueue, error) {
+	if config.Capacity < 1 {
+		return nil, fmt.Errorf("push queue capacity must be at least 1, got %d", config.Capacity)
+	}
+	switch config.Overflow {
+	case OverflowDropOldest, OverflowCoalesce, OverflowSpill:
+	default:
+		return nil, fmt.Errorf("unknown push queue overflow policy %q", config.Overflow)
+	}
+
+	q := &PushQueue{config: config}
+	q.ready = sync.NewCond(&q.mu)
+
+	if config.Overflow == OverflowSpill {
+		if err := q.openSpill(); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// openSpill opens the spill file and counts the pushes already waiting in it
+func (q *PushQueue) openSpill() error {
+	dir := q.config.SpillDir
+	if dir == "" {
+		dir = dataDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create spill directory: %v", err)
+	}
+	path := filepath.Join(dir, pushSpillFile)
+
+	writer, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open spill file %s: %v", path, err)
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to open spill file %s: %v", path, err)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxFileLineSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			q.spillDepth++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		writer.Close()
+		reader.Close()
+		return fmt.Errorf("failed to read spill file %s: %v", path, err)
+	}
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		writer.Close()
+		reader.Close()
+		return fmt.Errorf("failed to rewind spill file %s: %v", path, err)
+	}
+
+	if q.spillDepth > 0 {
+		log.Printf("Push queue: resuming %d spilled pushes from %s", q.spillDepth, path)
+	}
+	q.spillWriter = writer
+	q.spillFile = reader
+	q.spillReader = bufio.NewReader(reader)
+	return nil
+}
+
+// Enqueue adds a push to the queue without blocking
+func (q *PushQueue) Enqueue(req PushRequest) {
+	dropped, ok := q.enqueue(req)
+	if ok && q.config.Dropped != nil {
+		q.config.Dropped(dropped)
+	}
+}
+
+// enqueue adds a push to the queue, returning the push the overflow policy dropped, if any.
+// Pushes arriving after the queue is closed aren't reported, so they can be resumed later.
+func (q *PushQueue) enqueue(req PushRequest) (PushRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.dropped++
+		log.Printf("Push queue closed, dropping push to %s", req.DeviceToken)
+		return PushRequest{}, false
+	}
+	q.enqueued++
+
+	var dropped PushRequest
+	var ok bool
+	switch {
+	case q.spillDepth > 0:
+		// Keep pushes in order: nothing skips ahead of the ones already spilled
+		dropped, ok = q.spill(req)
+	case len(q.pending) < q.config.Capacity:
+		q.pending = append(q.pending, req)
+	default:
+		dropped, ok = q.overflow(req)
+	}
+
+	if depth := len(q.pending) + q.spillDepth; depth > q.highWater {
+		q.highWater = depth
+	}
+	q.ready.Signal()
+	return dropped, ok
+}
+
+// overflow applies the overflow policy to a push arriving at a full queue, returning the
+// push it dropped or replaced, if any
+func (q *PushQueue) overflow(req PushRequest) (PushRequest, bool) {
+	switch q.config.Overflow {
+	case OverflowSpill:
+		return q.spill(req)
+	case OverflowCoalesce:
+		for i := len(q.pending) - 1; i >= 0; i-- {
+			if q.pending[i].DeviceToken == req.DeviceToken {
+				superseded := q.pending[i]
+				q.pending[i] = req
+				q.coalesced++
+				return superseded, true
+			}
+		}
+	}
+
+	oldest := q.pending[0]
+	log.Printf("Push queue full, dropping oldest push to %s", oldest.DeviceToken)
+	q.pending = append(q.pending[1:], req)
+	q.dropped++
+	return oldest, true
+}
+
+// spill appends a push to the spill file, returning it as dropped if it can't be written
+func (q *PushQueue) spill(req PushRequest) (PushRequest, bool) {
+	line, err := json.Marshal(req)
+	if err == nil {
+		_, err = q.spillWriter.Write(append(line, '\n'))
+	}
+	if err != nil {
+		log.Printf("Push queue: failed to spill push to %s, dropping it: %v", req.DeviceToken, err)
+		q.dropped++
+		return req, true
+	}
+	q.spillDepth++
+	q.spilled++
+	return PushRequest{}, false
+}
+
+// refill moves up to a queue's worth of spilled pushes back into the queue, and empties
+// the spill file once everything in it has been read
+func (q *PushQueue) refill() {
+	for q.spillDepth > 0 && len(q.pending) < q.config.Capacity {
+		line, err := q.spillReader.ReadBytes('\n')
+		if err != nil {
+			log.Printf("Push queue: failed to read spill file, discarding %d spilled pushes: %v", q.spillDepth, err)
+			q.dropped += q.spillDepth
+			q.spillDepth = 0
+			break
+		}
+		if len(line) <= 1 {
+			continue
+		}
+		q.spillDepth--
+
+		var req PushRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			log.Printf("Push queue: skipping unreadable spilled push: %v", err)
+			q.dropped++
+			continue
+		}
+		q.pending = append(q.pending, req)
+	}
+
+	if q.spillDepth == 0 {
+		if err := q.spillWriter.Truncate(0); err != nil {
+			log.Printf("Push queue: failed to truncate spill file: %v", err)
+		}
+		if _, err := q.spillFile.Seek(0, io.SeekStart); err != nil {
+			log.Printf("Push queue: failed to rewind spill file: %v", err)
+		}
+		q.spillReader.Reset(q.spillFile)
+	}
+}
+
+// Dequeue waits for the next push. It returns false once the queue has been closed.
+func (q *PushQueue) Dequeue() (PushRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 {
+		for !q.closed && len(q.pending) == 0 && q.spillDepth == 0 {
+			q.ready.Wait()
+		}
+		if q.closed {
+			return PushRequest{}, false
+		}
+		// Nothing is refilled if every spilled push left was unreadable
+		if len(q.pending) == 0 {
+			q.refill()
+		}
+	}
+
+	req := q.pending[0]
+	q.pending = q.pending[1:]
+	return req, true
+}
+
+// Close stops the workers waiting on the queue. Spilled pushes stay in the spill file
+// for the next run; pushes still in memory are lost.
+func (q *PushQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	if q.spillWriter != nil {
+		q.spillWriter.Close()
+		q.spillFile.Close()
+	}
+	if len(q.pending) > 0 {
+		log.Printf("Push queue closed with %d pushes still pending", len(q.pending))
+	}
+	q.ready.Broadcast()
+}
+
+// GetStats returns the queue's depth and what the overflow policy has done so far
+func (q *PushQueue) GetStats() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return map[string]interface{}{
+		"depth":       len(q.pending),
+		"spill_depth": q.spillDepth,
+		"capacity":    q.config.Capacity,
+		"overflow":    q.config.Overflow,
+		"high_water":  q.highWater,
+		"enqueued":    q.enqueued,
+		"dropped":     q.dropped,
+		"coalesced":   q.coalesced,
+		"spilled":     q.spilled,
+	}
+}
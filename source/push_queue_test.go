@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPush is a push to a device, told apart from the device's other pushes by its status
+func testPush(token string, status string) PushRequest {
+	return PushRequest{DeviceToken: token, NewStatus: status}
+}
+
+func pushName(req PushRequest) string {
+	return req.DeviceToken + ":" + req.NewStatus
+}
+
+// dequeueN takes n pushes off a queue, failing the test if they don't arrive
+func dequeueN(t *testing.T, q *PushQueue, n int) []string {
+	t.Helper()
+	got := make(chan []string, 1)
+	go func() {
+		var names []string
+		for i := 0; i < n; i++ {
+			req, ok := q.Dequeue()
+			if !ok {
+				break
+			}
+			names = append(names, pushName(req))
+		}
+		got <- names
+	}()
+	select {
+	case names := <-got:
+		return names
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fewer than %d pushes were queued", n)
+		return nil
+	}
+}
+
+func TestPushQueueOverflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		overflow  string
+		enqueue   []PushRequest
+		want      []string
+		dropped   []string
+		coalesced int
+	}{
+		{
+			name:     "drop oldest",
+			overflow: OverflowDropOldest,
+			enqueue:  []PushRequest{testPush("a", "DOWN"), testPush("b", "DOWN"), testPush("a", "OPERATING")},
+			want:     []string{"b:DOWN", "a:OPERATING"},
+			dropped:  []string{"a:DOWN"},
+		},
+		{
+			name:      "coalesce replaces the device's pending push in place",
+			overflow:  OverflowCoalesce,
+			enqueue:   []PushRequest{testPush("a", "DOWN"), testPush("b", "DOWN"), testPush("a", "OPERATING")},
+			want:      []string{"a:OPERATING", "b:DOWN"},
+			dropped:   []string{"a:DOWN"},
+			coalesced: 1,
+		},
+		{
+			name:     "coalesce drops the oldest for a device without a pending push",
+			overflow: OverflowCoalesce,
+			enqueue:  []PushRequest{testPush("a", "DOWN"), testPush("b", "DOWN"), testPush("c", "DOWN")},
+			want:     []string{"b:DOWN", "c:DOWN"},
+			dropped:  []string{"a:DOWN"},
+		},
+		{
+			name:     "nothing is dropped below capacity",
+			overflow: OverflowCoalesce,
+			enqueue:  []PushRequest{testPush("a", "DOWN"), testPush("a", "OPERATING")},
+			want:     []string{"a:DOWN", "a:OPERATING"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dropped []string
+			q, err := NewPushQueue(PushQueueConfig{
+				Capacity: 2,
+				Overflow: tt.overflow,
+				Dropped:  func(req PushRequest) { dropped = append(dropped, pushName(req)) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+
+			for _, req := range tt.enqueue {
+				q.Enqueue(req)
+			}
+			if got := strings.Join(dropped, ","); got != strings.Join(tt.dropped, ",") {
+				t.Errorf("dropped %v, want %v", dropped, tt.dropped)
+			}
+			if coalesced := q.GetStats()["coalesced"]; coalesced != tt.coalesced {
+				t.Errorf("coalesced = %v, want %d", coalesced, tt.coalesced)
+			}
+			if got := dequeueN(t, q, len(tt.want)); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("dequeued %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushQueueSpill(t *testing.T) {
+	spilled := func(req PushRequest) string {
+		line, _ := json.Marshal(req)
+		return string(line)
+	}
+
+	tests := []struct {
+		name       string
+		previous   []string // lines left in the spill file by a previous run
+		enqueue    []PushRequest
+		spillDepth int // pushes waiting in the spill file before draining
+		want       []string
+		dropped    int
+	}{
+		{
+			name:       "pushes past capacity are spilled and delivered in order",
+			enqueue:    []PushRequest{testPush("a", "1"), testPush("b", "2"), testPush("c", "3"), testPush("d", "4"), testPush("e", "5")},
+			spillDepth: 3,
+			want:       []string{"a:1", "b:2", "c:3", "d:4", "e:5"},
+		},
+		{
+			name:       "the previous run's pushes come first",
+			previous:   []string{spilled(testPush("a", "1")), spilled(testPush("b", "2"))},
+			enqueue:    []PushRequest{testPush("c", "3")},
+			spillDepth: 3,
+			want:       []string{"a:1", "b:2", "c:3"},
+		},
+		{
+			name:       "unreadable spilled pushes are skipped",
+			previous:   []string{spilled(testPush("a", "1")), "not json", spilled(testPush("b", "2"))},
+			spillDepth: 3,
+			want:       []string{"a:1", "b:2"},
+			dropped:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, pushSpillFile)
+			if len(tt.previous) > 0 {
+				if err := os.WriteFile(path, []byte(strings.Join(tt.previous, "\n")+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			q, err := NewPushQueue(PushQueueConfig{Capacity: 2, Overflow: OverflowSpill, SpillDir: dir})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer q.Close()
+
+			for _, req := range tt.enqueue {
+				q.Enqueue(req)
+			}
+			if depth := q.GetStats()["spill_depth"]; depth != tt.spillDepth {
+				t.Errorf("spill_depth = %v before draining, want %d", depth, tt.spillDepth)
+			}
+			if got := dequeueN(t, q, len(tt.want)); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("dequeued %v, want %v", got, tt.want)
+			}
+
+			stats := q.GetStats()
+			if stats["depth"] != 0 || stats["spill_depth"] != 0 || stats["dropped"] != tt.dropped {
+				t.Errorf("stats after draining = %v, want an empty queue with %d dropped", stats, tt.dropped)
+			}
+			// Once everything spilled has been read the file is emptied
+			if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+				t.Errorf("spill file not emptied after draining: %v, %v", info, err)
+			}
+		})
+	}
+}
+
+func TestPushQueueSpillSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	config := PushQueueConfig{Capacity: 1, Overflow: OverflowSpill, SpillDir: dir}
+
+	q, err := NewPushQueue(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []PushRequest{testPush("a", "1"), testPush("b", "2"), testPush("c", "3")} {
+		q.Enqueue(req)
+	}
+	// The push held in memory is lost, the spilled ones stay in the file
+	q.Close()
+
+	resumed, err := NewPushQueue(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if depth := resumed.GetStats()["spill_depth"]; depth != 2 {
+		t.Fatalf("resumed spill_depth = %v, want 2", depth)
+	}
+	resumed.Enqueue(testPush("d", "4"))
+	if got := dequeueN(t, resumed, 3); strings.Join(got, ",") != "b:2,c:3,d:4" {
+		t.Errorf("dequeued %v after restart, want b:2,c:3,d:4", got)
+	}
+}
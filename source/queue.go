@@ -4,26 +4,52 @@ import (
 	"log"
 )
 
+// Push event types, sent to the app as the "eventType" payload key
+const (
+	EventStatusChange  = "status_change"
+	EventWaitTime      = "wait_time"
+	EventQueueOpened   = "queue_opened"
+	EventBoardingGroup = "boarding_group"
+)
+
 type PushRequest struct {
-	DeviceToken string
-	Message     string
-	EntityID    string
-	ParkID      string
-	OldStatus   string
-	NewStatus   string
-	OldWaitTime int
-	NewWaitTime int
-	Environment string // "development" or "production"
+	DeviceToken string `json:"deviceToken"`
+	EventType   string `json:"eventType"`
+	EntityID    string `json:"entityId"`
+	ParkID      string `json:"parkId"`
+	OldStatus   string `json:"oldStatus"`
+	NewStatus   string `json:"newStatus"`
+	OldWaitTime int    `json:"oldWaitTime"`
+	NewWaitTime int    `json:"newWaitTime"`
+	QueueType   string `json:"queueType,omitempty"` // set for queue events
+	Environment string `json:"environment"`         // "development" or "production"
+
+	// Visible alert text, set for devices that want alerts; silent background push if Body is empty
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+	Sound string `json:"sound,omitempty"`
+
+	// Failed attempts to send the push so far, while it waits to be retried
+	Attempts int `json:"attempts,omitempty"`
+
+	// Set once the push is in the outbox: its row and the lease it was queued under
+	OutboxID int64 `json:"outboxId,omitempty"`
+	LeaseID  int64 `json:"leaseId,omitempty"`
 }
 
 // EntityQueue is a buffered channel for entity updates
 var EntityQueue = make(chan Entity, 1000)
 
-// PushQueue is for push notifications
-var PushQueue = make(chan PushRequest, 100)
+// pushQueue holds push notifications for the APNS workers, created at startup
+var pushQueue *PushQueue
+
+// pushOutbox persists pushes until they have been sent, created at startup
+var pushOutbox *PushOutbox
 
+// Push hands a push notification to the outbox, which stores and queues it, without blocking.
+// If the queue is full its overflow policy decides which push gives way.
 func Push(req PushRequest) {
-	PushQueue <- req
+	pushOutbox.Add(req)
 }
 
 // QueueEntity adds an entity to the processing queue
@@ -0,0 +1,78 @@
+package main
+
+// Queue types reported in an entity's queue data
+const (
+	QueueStandby        = "STANDBY"
+	QueueSingleRider    = "SINGLE_RIDER"
+	QueuePaidStandby    = "PAID_STANDBY"
+	QueueReturnTime     = "RETURN_TIME"
+	QueuePaidReturnTime = "PAID_RETURN_TIME"
+	QueueBoardingGroup  = "BOARDING_GROUP"
+)
+
+// QueueAvailable is the state of a return time or boarding group queue that is accepting guests
+const QueueAvailable = "AVAILABLE"
+
+// QueueData is one of an entity's queues, keyed by queue type. Which fields are set
+// depends on the type: STANDBY, SINGLE_RIDER and PAID_STANDBY carry a wait time,
+// RETURN_TIME and PAID_RETURN_TIME a state and return window, and BOARDING_GROUP an
+// allocation status and the boarding groups currently being called.
+type QueueData struct {
+	WaitTime           *int        `json:"waitTime,omitempty"`
+	State              string      `json:"state,omitempty"`
+	ReturnStart        *string     `json:"returnStart,omitempty"`
+	ReturnEnd          *string     `json:"returnEnd,omitempty"`
+	Price              *QueuePrice `json:"price,omitempty"`
+	AllocationStatus   string      `json:"allocationStatus,omitempty"`
+	CurrentGroupStart  *int        `json:"currentGroupStart,omitempty"`
+	CurrentGroupEnd    *int        `json:"currentGroupEnd,omitempty"`
+	NextAllocationTime *string     `json:"nextAllocationTime,omitempty"`
+	EstimatedWait      *int        `json:"estimatedWait,omitempty"`
+}
+
+// QueuePrice is the price of a paid queue
+type QueuePrice struct {
+	Amount    int    `json:"amount"`
+	Currency  string `json:"currency"`
+	Formatted string `json:"formatted,omitempty"`
+}
+
+// Availability returns the queue's state: the return time state, or the boarding group allocation status
+func (q QueueData) Availability() string {
+	if q.State != "" {
+		return q.State
+	}
+	return q.AllocationStatus
+}
+
+// isKnownQueueType reports whether queueType is one of the queue types the feed reports
+func isKnownQueueType(queueType string) bool {
+	switch queueType {
+	case QueueStandby, QueueSingleRider, QueuePaidStandby, QueueReturnTime, QueuePaidReturnTime, QueueBoardingGroup:
+		return true
+	}
+	return false
+}
+
+// queueChanged reports whether a queue opened, closed or started calling different boarding groups
+func queueChanged(oldQueue QueueData, newQueue QueueData) bool {
+	return oldQueue.Availability() != newQueue.Availability() ||
+		!sameInt(oldQueue.CurrentGroupStart, newQueue.CurrentGroupStart) ||
+		!sameInt(oldQueue.CurrentGroupEnd, newQueue.CurrentGroupEnd)
+}
+
+// sameInt reports whether two optional integers are both unset or equal
+func sameInt(a *int, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// standbyWait returns the STANDBY wait time from an entity's queues, or 0 if there is none
+func standbyWait(queues map[string]QueueData) int {
+	if standby, exists := queues[QueueStandby]; exists && standby.WaitTime != nil {
+		return *standby.WaitTime
+	}
+	return 0
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Reconciler periodically re-polls each park's REST live endpoint and corrects any
+// entities that have drifted from it, e.g. because a WebSocket message was missed or
+// the entity queue overflowed. Corrections go through the entity queue like live updates,
+// so they are applied in order with them and missed transitions still produce
+// StatusChangeMessages. The WebSocket client also triggers
+// a gap fill after every reconnect to catch up on changes made while it was down.
+type Reconciler struct {
+	restClient    *RestClient
+	entityManager *EntityManager
+	interval      time.Duration
+
+	stats struct {
+		sync.RWMutex
+		runs                uint64
+		statusCorrections   uint64
+		waitTimeCorrections uint64
+		newEntities         uint64
+		lastRun             time.Time
+		gapFills            uint64
+		lastGap             time.Duration
+		lastGapCorrections  int
+		lastGapFill         time.Time
+	}
+}
+
+// NewReconciler creates a reconciler that runs every interval
+func NewReconciler(restClient *RestClient, entityManager *EntityManager, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		restClient:    restClient,
+		entityManager: entityManager,
+		interval:      interval,
+	}
+}
+
+// Start runs a reconciliation pass on every interval. It blocks, so call it in a goroutine.
+func (r *Reconciler) Start() {
+	log.Printf("Starting REST reconciler (interval %v)", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.ReconcileAll()
+	}
+}
+
+// ReconcileAll reconciles every enabled park
+func (r *Reconciler) ReconcileAll() {
+	corrections := r.reconcileEnabledParks()
+
+	r.stats.Lock()
+	r.stats.runs++
+	r.stats.lastRun = time.Now()
+	r.stats.Unlock()
+
+	if corrections > 0 {
+		log.Printf("Reconciler: corrected %d entities", corrections)
+	}
+}
+
+// GapFill reconciles every enabled park after the WebSocket reconnects, so status
+// changes made during the gap still notify devices
+func (r *Reconciler) GapFill(gap time.Duration) {
+	log.Printf("Reconciler: filling %v gap after reconnect", gap.Round(time.Second))
+	corrections := r.reconcileEnabledParks()
+
+	r.stats.Lock()
+	r.stats.gapFills++
+	r.stats.lastGap = gap
+	r.stats.lastGapCorrections = corrections
+	r.stats.lastGapFill = time.Now()
+	r.stats.Unlock()
+
+	log.Printf("Reconciler: gap fill corrected %d entities", corrections)
+}
+
+// reconcileEnabledParks reconciles every enabled park and returns the number of corrections made
+func (r *Reconciler) reconcileEnabledParks() int {
+	corrections := 0
+	for _, park := range r.restClient.parkManager.GetEnabledParks() {
+		count, err := r.ReconcilePark(park)
+		if err != nil {
+			log.Printf("Reconciler: error fetching entities for park %s: %v", park.Name, err)
+			continue // Continue with other parks even if one fails
+		}
+		corrections += count
+
+		// Small delay between requests to be respectful to the API
+		time.Sleep(100 * time.Millisecond)
+	}
+	return corrections
+}
+
+// ReconcilePark fetches a park's live data and queues every entity that differs from the
+// entity manager. It returns the number of corrections made.
+func (r *Reconciler) ReconcilePark(park Park) (int, error) {
+	restEntities, err := r.restClient.fetchParkEntities(park.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	corrections := 0
+	for _, restEntity := range restEntities {
+		entity, ok := toEntity(restEntity)
+		if !ok {
+			continue
+		}
+
+		existing, exists := r.entityManager.GetEntity(entity.EntityID)
+		if !exists {
+			QueueEntity(entity)
+			r.countCorrection(&r.stats.newEntities)
+			corrections++
+			continue
+		}
+
+		statusDiffers := existing.Status != entity.Status
+		waitTimeDiffers := existing.WaitTime != entity.WaitTime
+		if !statusDiffers && !waitTimeDiffers {
+			continue
+		}
+
+		// Don't let an older REST snapshot overwrite a newer WebSocket update. ProcessEntity
+		// checks again when the correction is applied, in case an update arrives meanwhile.
+		if entity.OlderThan(existing) {
+			continue
+		}
+
+		log.Printf("Reconciler: correcting %s (%s) status %s -> %s, wait %d -> %d",
+			entity.Name, entity.EntityID, existing.Status, entity.Status, existing.WaitTime, entity.WaitTime)
+
+		QueueEntity(entity)
+		if statusDiffers {
+			r.countCorrection(&r.stats.statusCorrections)
+		}
+		if waitTimeDiffers {
+			r.countCorrection(&r.stats.waitTimeCorrections)
+		}
+		corrections++
+	}
+
+	return corrections, nil
+}
+
+// countCorrection increments one of the correction counters
+func (r *Reconciler) countCorrection(counter *uint64) {
+	r.stats.Lock()
+	defer r.stats.Unlock()
+	*counter++
+}
+
+// GetStats returns the reconciler's counters for the metrics endpoint
+func (r *Reconciler) GetStats() map[string]interface{} {
+	r.stats.RLock()
+	defer r.stats.RUnlock()
+
+	return map[string]interface{}{
+		"interval_seconds":      r.interval.Seconds(),
+		"runs":                  r.stats.runs,
+		"status_corrections":    r.stats.statusCorrections,
+		"wait_time_corrections": r.stats.waitTimeCorrections,
+		"new_entities":          r.stats.newEntities,
+		"last_run":              r.stats.lastRun,
+		"gap_fills":             r.stats.gapFills,
+		"last_gap_seconds":      r.stats.lastGap.Seconds(),
+		"last_gap_corrections":  r.stats.lastGapCorrections,
+		"last_gap_fill":         r.stats.lastGapFill,
+	}
+}
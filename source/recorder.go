@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SourceReplay replays frames captured by a FrameRecorder through the WebSocket message handler
+const SourceReplay = "replay"
+
+// RecordedFrame is one line of a recording: a raw WebSocket frame and when it was received.
+// Frames that aren't valid JSON are stored as a JSON string.
+type RecordedFrame struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Frame      json.RawMessage `json:"frame"`
+}
+
+// FrameRecorder appends every raw WebSocket frame to newline-delimited JSON files in a
+// directory, starting a new file once the current one reaches maxBytes
+type FrameRecorder struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	sequence int
+}
+
+// NewFrameRecorder creates a recorder that writes to dir, creating it if needed
+func NewFrameRecorder(dir string, maxBytes int64) (*FrameRecorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %v", err)
+	}
+	return &FrameRecorder{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// Record writes a frame with the current time
+func (r *FrameRecorder) Record(frame []byte) {
+	record := RecordedFrame{ReceivedAt: time.Now()}
+	if json.Valid(frame) {
+		record.Frame = frame
+	} else {
+		record.Frame, _ = json.Marshal(string(frame))
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Recorder: failed to encode frame: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil || r.size+int64(len(line)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			log.Printf("Recorder: %v", err)
+			return
+		}
+	}
+
+	n, err := r.writer.Write(line)
+	r.size += int64(n)
+	if err == nil {
+		// Flush every frame so a crash loses nothing and the file can be tailed while recording
+		err = r.writer.Flush()
+	}
+	if err != nil {
+		log.Printf("Recorder: failed to write frame: %v", err)
+	}
+}
+
+// rotate closes the current file and opens a new one named after the current time.
+// The sequence number keeps names unique and in order when files fill up quickly.
+func (r *FrameRecorder) rotate() error {
+	r.closeFile()
+
+	r.sequence++
+	name := filepath.Join(r.dir, fmt.Sprintf("frames-%s-%04d.ndjson", time.Now().UTC().Format("20060102-150405"), r.sequence))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", name, err)
+	}
+
+	log.Printf("Recording WebSocket frames to %s", name)
+	r.file = file
+	r.writer = bufio.NewWriter(file)
+	r.size = 0
+	return nil
+}
+
+// closeFile flushes and closes the current file, if any
+func (r *FrameRecorder) closeFile() {
+	if r.file == nil {
+		return
+	}
+	if err := r.writer.Flush(); err != nil {
+		log.Printf("Recorder: failed to flush %s: %v", r.file.Name(), err)
+	}
+	r.file.Close()
+	r.file = nil
+	r.writer = nil
+}
+
+// Close flushes and closes the current file
+func (r *FrameRecorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeFile()
+}
+
+// ReplaySource feeds a recording back through the WebSocket client's message handler,
+// preserving the gaps between frames divided by speed. A speed of 0 replays without pauses.
+type ReplaySource struct {
+	path     string
+	speed    int
+	wsClient *WebSocketClient
+	done     chan struct{}
+}
+
+// NewReplaySource creates a source that replays the recording at path
+func NewReplaySource(path string, speed int, wsClient *WebSocketClient) *ReplaySource {
+	return &ReplaySource{
+		path:     path,
+		speed:    speed,
+		wsClient: wsClient,
+		done:     make(chan struct{}),
+	}
+}
+
+// Name identifies the source in logs and metrics
+func (r *ReplaySource) Name() string {
+	return SourceReplay
+}
+
+// Start replays the recording and returns once it is exhausted or the source is closed
+func (r *ReplaySource) Start() {
+	if err := r.replay(); err != nil {
+		log.Printf("Replay: %v", err)
+	}
+}
+
+// replay reads the recording and hands each frame to the message handler
+func (r *ReplaySource) replay() error {
+	file, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", r.path, err)
+	}
+	defer file.Close()
+
+	log.Printf("Replaying WebSocket frames from %s at %dx speed", r.path, r.speed)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxFileLineSize)
+
+	var previous time.Time
+	replayed := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record RecordedFrame
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Printf("Replay: skipping line %d: %v", line, err)
+			continue
+		}
+
+		if r.speed > 0 && !previous.IsZero() {
+			if gap := record.ReceivedAt.Sub(previous) / time.Duration(r.speed); gap > 0 {
+				select {
+				case <-r.done:
+					log.Printf("Replay closed after %d frames", replayed)
+					return nil
+				case <-time.After(gap):
+				}
+			}
+		}
+		previous = record.ReceivedAt
+
+		// Frames that weren't valid JSON were recorded as strings
+		frame := []byte(record.Frame)
+		var text string
+		if json.Unmarshal(record.Frame, &text) == nil {
+			frame = []byte(text)
+		}
+
+		r.wsClient.handleMessage(frame)
+		replayed++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %v", r.path, err)
+	}
+
+	log.Printf("Replay finished, replayed %d frames", replayed)
+	return nil
+}
+
+// Close stops the replay
+func (r *ReplaySource) Close() {
+	close(r.done)
+}
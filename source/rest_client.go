@@ -28,10 +28,7 @@ type LiveDataEntity struct {
 	LastUpdated  string                 `json:"lastUpdated"`
 	Queue        map[string]QueueData   `json:"queue,omitempty"`
 	OperatingHours []OperatingHour     `json:"operatingHours,omitempty"`
-}
-
-type QueueData struct {
-	WaitTime *int `json:"waitTime"`
+	Showtimes    []Showtime             `json:"showtimes,omitempty"`
 }
 
 type OperatingHour struct {
@@ -40,46 +37,103 @@ type OperatingHour struct {
 	EndTime   string `json:"endTime"`
 }
 
-// RestClient handles REST API calls to pre-populate entity data
+// Showtime is one performance of a SHOW entity
+type Showtime struct {
+	Type      string `json:"type"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime,omitempty"`
+}
+
+// RestClient handles REST API calls to pre-populate entity data. It is also a
+// LiveDataSource that polls every enabled park on pollInterval.
 type RestClient struct {
-	baseURL string
-	apiKey  string
-	client  *http.Client
+	baseURL      string
+	apiKey       string
+	client       *http.Client
+	parkManager  *ParkManager
+	pollInterval time.Duration
+	done         chan struct{}
 }
 
-// NewRestClient creates a new REST client
-func NewRestClient(apiKey string) *RestClient {
+// NewRestClient creates a new REST client for the given themeparks.wiki entity API base URL
+func NewRestClient(baseURL string, apiKey string, parkManager *ParkManager, pollInterval time.Duration) *RestClient {
 	return &RestClient{
-		baseURL: "https://api.themeparks.wiki/v1/entity",
+		baseURL: baseURL,
 		apiKey:  apiKey,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		parkManager:  parkManager,
+		pollInterval: pollInterval,
+		done:         make(chan struct{}),
 	}
 }
 
-// PrePopulateEntities fetches data from all parks and pre-populates the entity manager
+// Name identifies the source in logs and metrics
+func (rc *RestClient) Name() string {
+	return SourceREST
+}
+
+// Start polls every enabled park on each poll interval until the client is closed
+func (rc *RestClient) Start() {
+	log.Printf("Polling REST API every %v", rc.pollInterval)
+
+	ticker := time.NewTicker(rc.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-rc.done:
+			return
+		case <-ticker.C:
+			rc.PollEntities()
+		}
+	}
+}
+
+// Close stops polling
+func (rc *RestClient) Close() {
+	close(rc.done)
+}
+
+// PollEntities fetches every enabled park once and queues its entities, returning how many were queued
+func (rc *RestClient) PollEntities() int {
+	queued := 0
+	for _, park := range rc.parkManager.GetEnabledParks() {
+		restEntities, err := rc.fetchParkEntities(park.ID)
+		if err != nil {
+			log.Printf("Polling: error fetching entities for park %s: %v", park.Name, err)
+			continue // Continue with other parks even if one fails
+		}
+
+		for _, restEntity := range restEntities {
+			if entity, ok := toEntity(restEntity); ok {
+				QueueEntity(entity)
+				queued++
+			}
+		}
+
+		// Small delay between requests to be respectful to the API
+		time.Sleep(100 * time.Millisecond)
+	}
+	return queued
+}
+
+// PrePopulateEntities fetches data from all enabled parks and pre-populates the entity manager
 func (rc *RestClient) PrePopulateEntities(entityManager *EntityManager) error {
 	log.Printf("Starting pre-population of entities from REST API...")
 	
 	totalEntities := 0
 	
 	// Fetch data for each park
-	for _, park := range parks {
-		log.Printf("Fetching entities for park: %s (%s)", park.Name, park.ID)
-		
-		entities, err := rc.fetchParkEntities(park.ID)
+	for _, park := range rc.parkManager.GetEnabledParks() {
+		count, err := rc.PrePopulatePark(park, entityManager)
 		if err != nil {
 			log.Printf("Error fetching entities for park %s: %v", park.Name, err)
 			continue // Continue with other parks even if one fails
 		}
-		
-		// Convert and add entities to the manager
-		count := rc.addEntitiesToManager(entities, entityManager)
 		totalEntities += count
 		
-		log.Printf("Added %d entities for park %s", count, park.Name)
-		
 		// Small delay between requests to be respectful to the API
 		time.Sleep(100 * time.Millisecond)
 	}
@@ -88,13 +142,57 @@ func (rc *RestClient) PrePopulateEntities(entityManager *EntityManager) error {
 	return nil
 }
 
-// fetchParkEntities fetches live data for a specific park
+// PrePopulatePark fetches data for a single park and pre-populates the entity manager
+func (rc *RestClient) PrePopulatePark(park Park, entityManager *EntityManager) (int, error) {
+	log.Printf("Fetching entities for park: %s (%s)", park.Name, park.ID)
+	
+	entities, err := rc.fetchParkEntities(park.ID)
+	if err != nil {
+		return 0, err
+	}
+	
+	// Convert and add entities to the manager
+	count := rc.addEntitiesToManager(entities, entityManager)
+	log.Printf("Added %d entities for park %s", count, park.Name)
+	return count, nil
+}
+
+// fetchParkEntities fetches live data for a specific park, keeping the entity types the park tracks
 func (rc *RestClient) fetchParkEntities(parkID string) ([]LiveDataEntity, error) {
-	url := fmt.Sprintf("%s/%s/live?entityType=ATTRACTION", rc.baseURL, parkID)
+	park, _ := rc.parkManager.GetPark(parkID)
+	entityTypes := park.TrackedEntityTypes()
+
+	// The API filters by a single entity type; for several, fetch everything and filter here
+	url := fmt.Sprintf("%s/%s/live", rc.baseURL, parkID)
+	if len(entityTypes) == 1 {
+		url += "?entityType=" + entityTypes[0]
+	}
+	
+	var response ParkLiveDataResponse
+	if err := rc.getJSON(url, &response); err != nil {
+		return nil, err
+	}
 	
+	// Keep the park's timezone and the individual parks inside it up to date
+	if response.Timezone != "" {
+		rc.parkManager.SetParkTimezone(parkID, response.Timezone)
+	}
+	var tracked []LiveDataEntity
+	for _, entity := range response.LiveData {
+		rc.parkManager.RegisterChildPark(parkID, entity.ParkID)
+		if park.Tracks(entity.EntityType) {
+			tracked = append(tracked, entity)
+		}
+	}
+	
+	return tracked, nil
+}
+
+// getJSON makes an authenticated GET request and decodes the JSON response into target
+func (rc *RestClient) getJSON(url string, target interface{}) error {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return fmt.Errorf("failed to create request: %v", err)
 	}
 	
 	// Add API key header
@@ -103,26 +201,25 @@ func (rc *RestClient) fetchParkEntities(parkID string) ([]LiveDataEntity, error)
 	
 	resp, err := rc.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		return fmt.Errorf("failed to make request: %v", err)
 	}
 	defer resp.Body.Close()
 	
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 	
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return fmt.Errorf("failed to read response body: %v", err)
 	}
 	
-	var response ParkLiveDataResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON response: %v", err)
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %v", err)
 	}
 	
-	return response.LiveData, nil
+	return nil
 }
 
 // addEntitiesToManager converts REST API entities to our Entity format and adds them to the manager
@@ -130,41 +227,11 @@ func (rc *RestClient) addEntitiesToManager(restEntities []LiveDataEntity, entity
 	count := 0
 	
 	for _, restEntity := range restEntities {
-		// Only process ATTRACTION entities
-		if restEntity.EntityType != "ATTRACTION" {
+		entity, ok := toEntity(restEntity)
+		if !ok {
 			continue
 		}
 		
-		// Parse last updated time
-		lastUpdated, err := time.Parse(time.RFC3339, restEntity.LastUpdated)
-		if err != nil {
-			log.Printf("Warning: Could not parse lastUpdated for entity %s: %v", restEntity.ID, err)
-			lastUpdated = time.Now()
-		}
-		
-		// Extract wait time from queue data
-		waitTime := 0
-		if restEntity.Queue != nil {
-			if standby, exists := restEntity.Queue["STANDBY"]; exists && standby.WaitTime != nil {
-				waitTime = *standby.WaitTime
-			}
-		}
-		
-		// Convert status string to EntityStatus
-		status := EntityStatus(restEntity.Status)
-		
-		// Create our Entity format
-		entity := Entity{
-			EntityID:           restEntity.ID,
-			Name:              restEntity.Name,
-			EntityType:        restEntity.EntityType,
-			ParkID:            restEntity.ParkID,
-			WaitTime:          waitTime,
-			Status:            status,
-			LastStatusChange:  lastUpdated,
-			LastWaitTimeChange: lastUpdated,
-		}
-		
 		// Add to entity manager (this will not trigger status change notifications since it's initial population)
 		entityManager.UpdateEntity(entity)
 		count++
@@ -173,6 +240,57 @@ func (rc *RestClient) addEntitiesToManager(restEntities []LiveDataEntity, entity
 	return count
 }
 
+// toEntity converts a REST API entity to our Entity format. Entity types we can't track are skipped.
+func toEntity(restEntity LiveDataEntity) (Entity, bool) {
+	// Only process attractions, shows and restaurants
+	if !isTrackableEntityType(restEntity.EntityType) {
+		return Entity{}, false
+	}
+	
+	// Parse last updated time
+	upstreamUpdated := parseLastUpdated(restEntity.ID, restEntity.LastUpdated)
+	lastUpdated := upstreamUpdated
+	if lastUpdated.IsZero() {
+		lastUpdated = time.Now()
+	}
+	
+	// Extract wait time from queue data
+	waitTime := standbyWait(restEntity.Queue)
+	
+	// Convert status string to EntityStatus
+	status := EntityStatus(restEntity.Status)
+	
+	// Create our Entity format
+	return Entity{
+		EntityID:           restEntity.ID,
+		Name:              restEntity.Name,
+		EntityType:        restEntity.EntityType,
+		ParkID:            restEntity.ParkID,
+		WaitTime:          waitTime,
+		Status:            status,
+		LastStatusChange:  lastUpdated,
+		LastWaitTimeChange: lastUpdated,
+		OperatingHours:    restEntity.OperatingHours,
+		Queues:            restEntity.Queue,
+		Showtimes:         restEntity.Showtimes,
+		LastUpdated:       upstreamUpdated,
+	}, true
+}
+
+// parseLastUpdated parses an upstream lastUpdated time, returning the zero time if it is
+// missing or can't be parsed
+func parseLastUpdated(entityID string, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	lastUpdated, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("Warning: Could not parse lastUpdated for entity %s: %v", entityID, err)
+		return time.Time{}
+	}
+	return lastUpdated
+}
+
 // GetEntityCount returns the current number of entities in the manager
 func (rc *RestClient) GetEntityCount(entityManager *EntityManager) int {
 	entities := entityManager.GetAllEntities()
@@ -192,13 +310,7 @@ func (rc *RestClient) GetEntityStats(entityManager *EntityManager) map[string]in
 	// Count entities by park
 	for _, entity := range entities {
 		// Count by park
-		parkName := "Unknown"
-		for _, park := range parks {
-			if park.ID == entity.ParkID {
-				parkName = park.Name
-				break
-			}
-		}
+		parkName := rc.parkManager.GetParkName(entity.ParkID)
 		stats["parks"].(map[string]int)[parkName]++
 		
 		// Count by status
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ParkScheduleResponse is the /entity/{id}/schedule response. Destinations
+// list a schedule per park; parks carry their own schedule at the top level.
+type ParkScheduleResponse struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	EntityType string         `json:"entityType"`
+	Timezone   string         `json:"timezone"`
+	Schedule   []ScheduleData `json:"schedule"`
+	Parks      []struct {
+		ID       string         `json:"id"`
+		Name     string         `json:"name"`
+		Timezone string         `json:"timezone"`
+		Schedule []ScheduleData `json:"schedule"`
+	} `json:"parks"`
+}
+
+type ScheduleData struct {
+	Date        string `json:"date"`
+	Type        string `json:"type"`
+	OpeningTime string `json:"openingTime"`
+	ClosingTime string `json:"closingTime"`
+	Description string `json:"description,omitempty"`
+}
+
+// ScheduleEntry is one opening window for a park on a local date (YYYY-MM-DD)
+type ScheduleEntry struct {
+	ParkID      string `json:"parkId"`
+	Date        string `json:"date"`
+	Type        string `json:"type"`
+	OpeningTime string `json:"openingTime"`
+	ClosingTime string `json:"closingTime"`
+	Timezone    string `json:"timezone"`
+	Description string `json:"description,omitempty"`
+}
+
+// StartScheduleFetcher refreshes every enabled park's schedule now and then on each interval
+func (rc *RestClient) StartScheduleFetcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			rc.RefreshSchedules()
+			<-ticker.C
+		}
+	}()
+}
+
+// RefreshSchedules fetches and stores the schedule of every enabled park
+func (rc *RestClient) RefreshSchedules() {
+	log.Printf("Refreshing park schedules...")
+
+	for _, park := range rc.parkManager.GetEnabledParks() {
+		if err := rc.RefreshParkSchedule(park.ID); err != nil {
+			log.Printf("Error fetching schedule for park %s: %v", park.Name, err)
+			continue // Continue with other parks even if one fails
+		}
+
+		// Small delay between requests to be respectful to the API
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// RefreshParkSchedule fetches and stores the schedule for a configured park and the parks inside it
+func (rc *RestClient) RefreshParkSchedule(parkID string) error {
+	url := fmt.Sprintf("%s/%s/schedule", rc.baseURL, parkID)
+
+	var response ParkScheduleResponse
+	if err := rc.getJSON(url, &response); err != nil {
+		return err
+	}
+
+	if response.Timezone != "" {
+		rc.parkManager.SetParkTimezone(parkID, response.Timezone)
+	}
+
+	if len(response.Schedule) > 0 {
+		entries := toScheduleEntries(parkID, response.Timezone, response.Schedule)
+		if err := db.StoreParkSchedule(parkID, entries); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range response.Parks {
+		rc.parkManager.RegisterChildPark(parkID, child.ID)
+
+		timezone := child.Timezone
+		if timezone == "" {
+			timezone = response.Timezone
+		}
+		entries := toScheduleEntries(child.ID, timezone, child.Schedule)
+		if err := db.StoreParkSchedule(child.ID, entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// toScheduleEntries converts API schedule data into entries for a park
+func toScheduleEntries(parkID string, timezone string, schedule []ScheduleData) []ScheduleEntry {
+	entries := make([]ScheduleEntry, 0, len(schedule))
+	for _, data := range schedule {
+		entries = append(entries, ScheduleEntry{
+			ParkID:      parkID,
+			Date:        data.Date,
+			Type:        data.Type,
+			OpeningTime: data.OpeningTime,
+			ClosingTime: data.ClosingTime,
+			Timezone:    timezone,
+			Description: data.Description,
+		})
+	}
+	return entries
+}
+
+// scheduledHours returns an entity's operating hours, falling back to the span of a show's
+// performances, then to its park's OPERATING schedule windows around the given time when
+// the feed didn't provide any
+func scheduledHours(entity Entity, at time.Time) []OperatingHour {
+	if len(entity.OperatingHours) > 0 {
+		return entity.OperatingHours
+	}
+	if window, ok := showtimeWindow(entity.Showtimes); ok {
+		return []OperatingHour{window}
+	}
+
+	// Schedule dates are local to the park, so look a day either side of the UTC date
+	fromDate := at.UTC().AddDate(0, 0, -1).Format("2006-01-02")
+	toDate := at.UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	entries, err := db.GetParkSchedule([]string{entity.ParkID}, fromDate, toDate)
+	if err != nil {
+		log.Printf("Error getting schedule for park %s: %v", entity.ParkID, err)
+		return nil
+	}
+
+	var hours []OperatingHour
+	for _, entry := range entries {
+		if entry.Type == "OPERATING" {
+			hours = append(hours, OperatingHour{
+				Type:      entry.Type,
+				StartTime: entry.OpeningTime,
+				EndTime:   entry.ClosingTime,
+			})
+		}
+	}
+	return hours
+}
+
+// showtimeWindow returns a single window from a show's first performance to the end of its
+// last, so a show closing between performances still counts as an unexpected closure
+func showtimeWindow(showtimes []Showtime) (OperatingHour, bool) {
+	var first, last time.Time
+	for _, showtime := range showtimes {
+		start, err := time.Parse(time.RFC3339, showtime.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, showtime.EndTime)
+		if err != nil {
+			end = start
+		}
+
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+
+	if first.IsZero() {
+		return OperatingHour{}, false
+	}
+	return OperatingHour{
+		Type:      "SHOWTIMES",
+		StartTime: first.Format(time.RFC3339),
+		EndTime:   last.Format(time.RFC3339),
+	}, true
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testDestinationID = "destination"
+	testParkID        = "magic-kingdom"
+	testOtherParkID   = "epcot"
+)
+
+// destinationSchedule is a /entity/{id}/schedule response for a destination with its own
+// schedule and two parks, the second of which doesn't report a timezone
+const destinationSchedule = `{
+	"id": "destination",
+	"name": "Test Resort",
+	"entityType": "DESTINATION",
+	"timezone": "America/New_York",
+	"schedule": [
+		{"date": "2025-06-01", "type": "OPERATING", "openingTime": "2025-06-01T08:00:00-04:00", "closingTime": "2025-06-01T23:00:00-04:00"}
+	],
+	"parks": [
+		{
+			"id": "magic-kingdom",
+			"name": "Magic Kingdom",
+			"timezone": "America/Chicago",
+			"schedule": [
+				{"date": "2025-06-01", "type": "OPERATING", "openingTime": "2025-06-01T09:00:00-04:00", "closingTime": "2025-06-01T22:00:00-04:00"},
+				{"date": "2025-06-01", "type": "TICKETED_EVENT", "openingTime": "2025-06-01T19:00:00-04:00", "closingTime": "2025-06-01T23:00:00-04:00", "description": "After Hours"}
+			]
+		},
+		{
+			"id": "epcot",
+			"name": "EPCOT",
+			"schedule": [
+				{"date": "2025-06-02", "type": "OPERATING", "openingTime": "2025-06-02T09:00:00-04:00", "closingTime": "2025-06-02T21:00:00-04:00"}
+			]
+		}
+	]
+}`
+
+// newScheduleTestClient points a RestClient at handler, backed by a fresh database in a
+// temporary directory holding the given parks
+func newScheduleTestClient(t *testing.T, handler http.Handler, parks ...Park) (*RestClient, *ParkManager) {
+	t.Helper()
+	useTestDB(t)
+
+	for _, park := range parks {
+		if err := db.StorePark(park); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parkManager, err := NewParkManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewRestClient(server.URL, "test-key", parkManager, time.Minute), parkManager
+}
+
+// scheduleHandler serves destinationSchedule for the destination and 404s everything else
+func scheduleHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-API-Key"); got != "test-key" {
+			t.Errorf("X-API-Key = %q, want %q", got, "test-key")
+		}
+		if r.URL.Path != "/"+testDestinationID+"/schedule" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(destinationSchedule))
+	})
+}
+
+func getSchedule(t *testing.T, parkID string) []ScheduleEntry {
+	t.Helper()
+	entries, err := db.GetParkSchedule([]string{parkID}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestRefreshParkSchedule(t *testing.T) {
+	restClient, parkManager := newScheduleTestClient(t, scheduleHandler(t),
+		Park{ID: testDestinationID, Name: "Test Resort", Type: Disney, Enabled: true})
+
+	if err := restClient.RefreshParkSchedule(testDestinationID); err != nil {
+		t.Fatalf("RefreshParkSchedule: %v", err)
+	}
+
+	park, _ := parkManager.GetPark(testDestinationID)
+	if park.Timezone != "America/New_York" {
+		t.Errorf("destination timezone = %q, want America/New_York", park.Timezone)
+	}
+	for _, childID := range []string{testParkID, testOtherParkID} {
+		if park, ok := parkManager.ResolvePark(childID); !ok || park.ID != testDestinationID {
+			t.Errorf("ResolvePark(%q) = %q, %v, want the destination", childID, park.ID, ok)
+		}
+	}
+
+	destination := getSchedule(t, testDestinationID)
+	if len(destination) != 1 || destination[0].ClosingTime != "2025-06-01T23:00:00-04:00" {
+		t.Errorf("destination schedule = %+v", destination)
+	}
+
+	park1 := getSchedule(t, testParkID)
+	if len(park1) != 2 {
+		t.Fatalf("got %d entries for %s, want 2", len(park1), testParkID)
+	}
+	for _, entry := range park1 {
+		if entry.ParkID != testParkID || entry.Timezone != "America/Chicago" {
+			t.Errorf("entry %+v should belong to %s in America/Chicago", entry, testParkID)
+		}
+	}
+	var event ScheduleEntry
+	for _, entry := range park1 {
+		if entry.Type == "TICKETED_EVENT" {
+			event = entry
+		}
+	}
+	if event.Description != "After Hours" || event.OpeningTime != "2025-06-01T19:00:00-04:00" {
+		t.Errorf("ticketed event = %+v", event)
+	}
+
+	// A park without its own timezone takes the destination's
+	park2 := getSchedule(t, testOtherParkID)
+	if len(park2) != 1 || park2[0].Date != "2025-06-02" || park2[0].Timezone != "America/New_York" {
+		t.Errorf("%s schedule = %+v", testOtherParkID, park2)
+	}
+}
+
+func TestRefreshParkScheduleReplacesUpcomingDays(t *testing.T) {
+	restClient, _ := newScheduleTestClient(t, scheduleHandler(t),
+		Park{ID: testDestinationID, Name: "Test Resort", Type: Disney, Enabled: true})
+
+	stale := []ScheduleEntry{
+		{ParkID: testParkID, Date: "2025-05-31", Type: "OPERATING", OpeningTime: "2025-05-31T09:00:00-04:00", ClosingTime: "2025-05-31T22:00:00-04:00"},
+		{ParkID: testParkID, Date: "2025-06-02", Type: "OPERATING", OpeningTime: "2025-06-02T09:00:00-04:00", ClosingTime: "2025-06-02T22:00:00-04:00"},
+	}
+	if err := db.StoreParkSchedule(testParkID, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := restClient.RefreshParkSchedule(testDestinationID); err != nil {
+		t.Fatalf("RefreshParkSchedule: %v", err)
+	}
+
+	// Days before the new schedule are kept, days it covers onwards are replaced
+	dates := make(map[string]int)
+	for _, entry := range getSchedule(t, testParkID) {
+		dates[entry.Date]++
+	}
+	if dates["2025-05-31"] != 1 || dates["2025-06-01"] != 2 || dates["2025-06-02"] != 0 {
+		t.Errorf("schedule dates = %v", dates)
+	}
+}
+
+func TestRefreshParkScheduleAPIError(t *testing.T) {
+	restClient, parkManager := newScheduleTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}), Park{ID: testDestinationID, Name: "Test Resort", Type: Disney, Enabled: true})
+
+	if err := restClient.RefreshParkSchedule(testDestinationID); err == nil {
+		t.Fatal("RefreshParkSchedule succeeded on an API error")
+	}
+	if entries := getSchedule(t, testDestinationID); len(entries) != 0 {
+		t.Errorf("stored %d entries after an API error", len(entries))
+	}
+	if park, _ := parkManager.GetPark(testDestinationID); park.Timezone != "" {
+		t.Errorf("timezone set to %q after an API error", park.Timezone)
+	}
+}
+
+func TestStartScheduleFetcher(t *testing.T) {
+	requested := make(chan string, 10)
+	handler := scheduleHandler(t)
+	restClient, _ := newScheduleTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- r.URL.Path
+		handler.ServeHTTP(w, r)
+	}),
+		Park{ID: testDestinationID, Name: "Test Resort", Type: Disney, Enabled: true},
+		Park{ID: "disabled", Name: "Disabled Resort", Type: Universal, Enabled: false})
+
+	restClient.StartScheduleFetcher(time.Hour)
+
+	select {
+	case path := <-requested:
+		if path != "/"+testDestinationID+"/schedule" {
+			t.Fatalf("fetched %s, want the enabled destination's schedule", path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("schedule was not fetched on start")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(getSchedule(t, testOtherParkID)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("fetched schedule was not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case path := <-requested:
+		t.Errorf("unexpected request for %s", path)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestScheduledHoursFallsBackToParkSchedule(t *testing.T) {
+	restClient, _ := newScheduleTestClient(t, scheduleHandler(t),
+		Park{ID: testDestinationID, Name: "Test Resort", Type: Disney, Enabled: true})
+	if err := restClient.RefreshParkSchedule(testDestinationID); err != nil {
+		t.Fatalf("RefreshParkSchedule: %v", err)
+	}
+
+	at := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	hours := scheduledHours(Entity{EntityID: "ride", ParkID: testParkID}, at)
+	if len(hours) != 1 || hours[0].StartTime != "2025-06-01T09:00:00-04:00" || hours[0].EndTime != "2025-06-01T22:00:00-04:00" {
+		t.Fatalf("scheduledHours = %+v, want the park's OPERATING window", hours)
+	}
+
+	// 21:55 local is near closing time, 14:00 local is mid-afternoon
+	if !isScheduledClosure(hours, time.Date(2025, 6, 2, 1, 55, 0, 0, time.UTC)) {
+		t.Error("closing at the end of the park's hours should be scheduled")
+	}
+	if isScheduledClosure(hours, at) {
+		t.Error("closing in the middle of the park's hours should not be scheduled")
+	}
+}
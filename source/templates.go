@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// defaultLocale is used for devices without a locale, and when a device's locale has no template
+const defaultLocale = "en"
+
+// NotificationTemplate is the title and body of an alert, as text/template source rendered
+// with NotificationData
+type NotificationTemplate struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// NotificationData is what alert templates are rendered with
+type NotificationData struct {
+	Entity      string // entity name, or its ID if it has none
+	EntityID    string
+	Park        string // park name, empty for parks that aren't configured
+	ParkID      string
+	OldStatus   string
+	NewStatus   string
+	OldWaitTime int
+	NewWaitTime int
+	QueueType   string
+	GroupStart  int // boarding groups being called
+	GroupEnd    int
+
+	// Localized names for OldStatus, NewStatus and QueueType, set when the alert is rendered
+	OldStatusName string
+	NewStatusName string
+	QueueName     string
+}
+
+// defaultLabels are the localized names of entity statuses and queue types, by locale, for
+// templates that don't have a qualified variant for every status or queue type. Values
+// without a name are shown as they are.
+var defaultLabels = map[string]map[string]string{
+	"en": {
+		string(StatusOperating):     "operating",
+		string(StatusDown):          "temporarily down",
+		string(StatusClosed):        "closed",
+		string(StatusRefurbishment): "closed for refurbishment",
+		QueueStandby:                "standby",
+		QueueSingleRider:            "single rider",
+		QueuePaidStandby:            "paid standby",
+		QueueReturnTime:             "return times",
+		QueuePaidReturnTime:         "paid return times",
+		QueueBoardingGroup:          "virtual queue",
+	},
+	"ja": {
+		string(StatusOperating):     "運営中",
+		string(StatusDown):          "一時運営中止",
+		string(StatusClosed):        "運営終了",
+		string(StatusRefurbishment): "休止中",
+		QueueStandby:                "スタンバイ",
+		QueueSingleRider:            "シングルライダー",
+		QueuePaidStandby:            "有料スタンバイ",
+		QueueReturnTime:             "時間指定",
+		QueuePaidReturnTime:         "有料の時間指定",
+		QueueBoardingGroup:          "バーチャルキュー",
+	},
+	"fr": {
+		string(StatusOperating):     "en service",
+		string(StatusDown):          "interruption temporaire",
+		string(StatusClosed):        "fermé",
+		string(StatusRefurbishment): "en réhabilitation",
+		QueueStandby:                "file d'attente standard",
+		QueueSingleRider:            "file Single Rider",
+		QueuePaidStandby:            "file d'attente standard payante",
+		QueueReturnTime:             "créneaux de retour",
+		QueuePaidReturnTime:         "créneaux de retour payants",
+		QueueBoardingGroup:          "file d'attente virtuelle",
+	},
+	"zh": {
+		string(StatusOperating):     "运营中",
+		string(StatusDown):          "暂停运营",
+		string(StatusClosed):        "已关闭",
+		string(StatusRefurbishment): "维护中",
+		QueueStandby:                "普通排队",
+		QueueSingleRider:            "单人通道",
+		QueuePaidStandby:            "付费排队",
+		QueueReturnTime:             "预约时段",
+		QueuePaidReturnTime:         "付费预约时段",
+		QueueBoardingGroup:          "虚拟排队",
+	},
+}
+
+// defaultNotificationTemplates are the built-in templates, by locale and then by key. A key is
+// an event type, optionally qualified with ".<qualifier>": the new status for status changes,
+// the queue type for queues opening, and AVAILABLE for boarding group alerts on the virtual
+// queue opening. Qualified templates are used in preference to the event type's template.
+var defaultNotificationTemplates = map[string]map[string]NotificationTemplate{
+	"en": {
+		EventStatusChange:                      {Title: "{{.Park}}", Body: "{{.Entity}} is now {{.NewStatusName}}"},
+		EventStatusChange + ".OPERATING":       {Title: "{{.Park}}", Body: `{{.Entity}} {{if or (eq .OldStatus "CLOSED") (eq .OldStatus "REFURBISHMENT")}}is open{{else}}is back up{{end}}{{if gt .NewWaitTime 0}} — {{.NewWaitTime}} min wait{{end}}`},
+		EventStatusChange + ".DOWN":            {Title: "{{.Park}}", Body: "{{.Entity}} is temporarily down"},
+		EventStatusChange + ".CLOSED":          {Title: "{{.Park}}", Body: "{{.Entity}} has closed"},
+		EventStatusChange + ".REFURBISHMENT":   {Title: "{{.Park}}", Body: "{{.Entity}} is closed for refurbishment"},
+		EventWaitTime:                          {Title: "{{.Park}}", Body: "{{.Entity}} wait is down to {{.NewWaitTime}} min (was {{.OldWaitTime}} min)"},
+		EventQueueOpened:                       {Title: "{{.Park}}", Body: "{{.Entity}}: {{.QueueName}} now available"},
+		EventQueueOpened + ".RETURN_TIME":      {Title: "{{.Park}}", Body: "{{.Entity}}: return times now available"},
+		EventQueueOpened + ".PAID_RETURN_TIME": {Title: "{{.Park}}", Body: "{{.Entity}}: paid return times now available"},
+		EventQueueOpened + ".BOARDING_GROUP":   {Title: "{{.Park}}", Body: "{{.Entity}} virtual queue is open"},
+		EventBoardingGroup:                     {Title: "{{.Park}}", Body: "{{.Entity}} is calling boarding groups {{.GroupStart}}-{{.GroupEnd}}"},
+		EventBoardingGroup + ".AVAILABLE":      {Title: "{{.Park}}", Body: "{{.Entity}} virtual queue is open"},
+	},
+	"ja": {
+		EventStatusChange:                      {Title: "{{.Park}}", Body: "{{.Entity}}の状態が{{.NewStatusName}}になりました"},
+		EventStatusChange + ".OPERATING":       {Title: "{{.Park}}", Body: `{{.Entity}}{{if or (eq .OldStatus "CLOSED") (eq .OldStatus "REFURBISHMENT")}}の運営が始まりました{{else}}の運営が再開しました{{end}}{{if gt .NewWaitTime 0}} — 待ち時間{{.NewWaitTime}}分{{end}}`},
+		EventStatusChange + ".DOWN":            {Title: "{{.Park}}", Body: "{{.Entity}}は一時運営中止中です"},
+		EventStatusChange + ".CLOSED":          {Title: "{{.Park}}", Body: "{{.Entity}}の運営が終了しました"},
+		EventStatusChange + ".REFURBISHMENT":   {Title: "{{.Park}}", Body: "{{.Entity}}は休止中です"},
+		EventWaitTime:                          {Title: "{{.Park}}", Body: "{{.Entity}}の待ち時間が{{.NewWaitTime}}分になりました（以前は{{.OldWaitTime}}分）"},
+		EventQueueOpened:                       {Title: "{{.Park}}", Body: "{{.Entity}}：{{.QueueName}}が利用可能になりました"},
+		EventQueueOpened + ".RETURN_TIME":      {Title: "{{.Park}}", Body: "{{.Entity}}：時間指定の受付が始まりました"},
+		EventQueueOpened + ".PAID_RETURN_TIME": {Title: "{{.Park}}", Body: "{{.Entity}}：有料の時間指定の受付が始まりました"},
+		EventQueueOpened + ".BOARDING_GROUP":   {Title: "{{.Park}}", Body: "{{.Entity}}のバーチャルキューが始まりました"},
+		EventBoardingGroup:                     {Title: "{{.Park}}", Body: "{{.Entity}}：グループ{{.GroupStart}}〜{{.GroupEnd}}のご案内が始まりました"},
+		EventBoardingGroup + ".AVAILABLE":      {Title: "{{.Park}}", Body: "{{.Entity}}のバーチャルキューが始まりました"},
+	},
+	"fr": {
+		EventStatusChange:                      {Title: "{{.Park}}", Body: "{{.Entity}} : nouveau statut « {{.NewStatusName}} »"},
+		EventStatusChange + ".OPERATING":       {Title: "{{.Park}}", Body: `{{.Entity}} : {{if or (eq .OldStatus "CLOSED") (eq .OldStatus "REFURBISHMENT")}}ouverture{{else}}de nouveau en service{{end}}{{if gt .NewWaitTime 0}} — {{.NewWaitTime}} min d'attente{{end}}`},
+		EventStatusChange + ".DOWN":            {Title: "{{.Park}}", Body: "{{.Entity}} : interruption temporaire"},
+		EventStatusChange + ".CLOSED":          {Title: "{{.Park}}", Body: "{{.Entity}} : fermeture"},
+		EventStatusChange + ".REFURBISHMENT":   {Title: "{{.Park}}", Body: "{{.Entity}} : fermeture pour réhabilitation"},
+		EventWaitTime:                          {Title: "{{.Park}}", Body: "{{.Entity}} : l'attente est descendue à {{.NewWaitTime}} min (contre {{.OldWaitTime}} min)"},
+		EventQueueOpened:                       {Title: "{{.Park}}", Body: "{{.Entity}} : {{.QueueName}} disponible"},
+		EventQueueOpened + ".RETURN_TIME":      {Title: "{{.Park}}", Body: "{{.Entity}} : créneaux de retour disponibles"},
+		EventQueueOpened + ".PAID_RETURN_TIME": {Title: "{{.Park}}", Body: "{{.Entity}} : créneaux de retour payants disponibles"},
+		EventQueueOpened + ".BOARDING_GROUP":   {Title: "{{.Park}}", Body: "{{.Entity}} : la file d'attente virtuelle est ouverte"},
+		EventBoardingGroup:                     {Title: "{{.Park}}", Body: "{{.Entity}} : appel des groupes {{.GroupStart}} à {{.GroupEnd}}"},
+		EventBoardingGroup + ".AVAILABLE":      {Title: "{{.Park}}", Body: "{{.Entity}} : la file d'attente virtuelle est ouverte"},
+	},
+	"zh": {
+		EventStatusChange:                      {Title: "{{.Park}}", Body: "{{.Entity}}状态变为{{.NewStatusName}}"},
+		EventStatusChange + ".OPERATING":       {Title: "{{.Park}}", Body: `{{.Entity}}{{if or (eq .OldStatus "CLOSED") (eq .OldStatus "REFURBISHMENT")}}已开放{{else}}已恢复运营{{end}}{{if gt .NewWaitTime 0}} — 等候{{.NewWaitTime}}分钟{{end}}`},
+		EventStatusChange + ".DOWN":            {Title: "{{.Park}}", Body: "{{.Entity}}暂停运营"},
+		EventStatusChange + ".CLOSED":          {Title: "{{.Park}}", Body: "{{.Entity}}已关闭"},
+		EventStatusChange + ".REFURBISHMENT":   {Title: "{{.Park}}", Body: "{{.Entity}}因维护暂停开放"},
+		EventWaitTime:                          {Title: "{{.Park}}", Body: "{{.Entity}}等候时间降至{{.NewWaitTime}}分钟（原为{{.OldWaitTime}}分钟）"},
+		EventQueueOpened:                       {Title: "{{.Park}}", Body: "{{.Entity}}：{{.QueueName}}现已开放"},
+		EventQueueOpened + ".RETURN_TIME":      {Title: "{{.Park}}", Body: "{{.Entity}}：预约时段现已开放"},
+		EventQueueOpened + ".PAID_RETURN_TIME": {Title: "{{.Park}}", Body: "{{.Entity}}：付费预约时段现已开放"},
+		EventQueueOpened + ".BOARDING_GROUP":   {Title: "{{.Park}}", Body: "{{.Entity}}虚拟排队现已开放"},
+		EventBoardingGroup:                     {Title: "{{.Park}}", Body: "{{.Entity}}正在召集第{{.GroupStart}}-{{.GroupEnd}}组"},
+		EventBoardingGroup + ".AVAILABLE":      {Title: "{{.Park}}", Body: "{{.Entity}}虚拟排队现已开放"},
+	},
+}
+
+// compiledTemplate is a parsed NotificationTemplate
+type compiledTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// NotificationTemplates renders alerts from templates keyed by locale and event type: the
+// built-in templates, overridden and extended by an optional JSON file in the same shape as
+// defaultNotificationTemplates
+type NotificationTemplates struct {
+	path string
+
+	mu        sync.RWMutex
+	templates map[string]map[string]compiledTemplate
+	builtIn   map[string]map[string]compiledTemplate
+}
+
+// NewNotificationTemplates loads the built-in templates and, if path is set, the templates in that file
+func NewNotificationTemplates(path string) (*NotificationTemplates, error) {
+	builtIn, err := compileTemplates(defaultNotificationTemplates, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile built-in templates: %v", err)
+	}
+
+	t := &NotificationTemplates{path: path, builtIn: builtIn}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Reload reads the template file again. If it can't be read or a template in it doesn't
+// compile, the templates in use are kept.
+func (t *NotificationTemplates) Reload() error {
+	templates := t.builtIn
+	if t.path != "" {
+		data, err := os.ReadFile(t.path)
+		if err != nil {
+			return fmt.Errorf("failed to read templates file: %v", err)
+		}
+
+		var overrides map[string]map[string]NotificationTemplate
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			return fmt.Errorf("failed to parse templates file %s: %v", t.path, err)
+		}
+
+		templates, err = compileTemplates(overrides, t.builtIn)
+		if err != nil {
+			return fmt.Errorf("failed to compile templates file %s: %v", t.path, err)
+		}
+		log.Printf("Loaded notification templates for %d locale(s) from %s", len(overrides), t.path)
+	}
+
+	t.mu.Lock()
+	t.templates = templates
+	t.mu.Unlock()
+	return nil
+}
+
+// compileTemplates parses templates on top of a copy of base. Each template is also rendered
+// once with empty data, so references to fields that don't exist are caught here.
+func compileTemplates(sources map[string]map[string]NotificationTemplate, base map[string]map[string]compiledTemplate) (map[string]map[string]compiledTemplate, error) {
+	compiled := make(map[string]map[string]compiledTemplate)
+	for locale, templates := range base {
+		compiled[locale] = make(map[string]compiledTemplate, len(templates))
+		for key, tmpl := range templates {
+			compiled[locale][key] = tmpl
+		}
+	}
+
+	for locale, templates := range sources {
+		locale = normalizeLocale(locale)
+		if compiled[locale] == nil {
+			compiled[locale] = make(map[string]compiledTemplate)
+		}
+		for key, source := range templates {
+			title, err := parseTemplate(locale+"/"+key+"/title", source.Title)
+			if err != nil {
+				return nil, err
+			}
+			body, err := parseTemplate(locale+"/"+key+"/body", source.Body)
+			if err != nil {
+				return nil, err
+			}
+			compiled[locale][key] = compiledTemplate{title: title, body: body}
+		}
+	}
+	return compiled, nil
+}
+
+// parseTemplate parses one template and checks that it renders
+func parseTemplate(name string, source string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, NotificationData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Render renders the alert for an event in the device's locale. Locales fall back from the
+// most to the least specific, e.g. zh-Hans-CN, zh-Hans, zh, then to English; within a locale
+// the qualified template is preferred over the event type's.
+func (t *NotificationTemplates) Render(locale string, eventType string, qualifier string, data NotificationData) Alert {
+	t.mu.RLock()
+	templates := t.templates
+	t.mu.RUnlock()
+
+	tmpl, ok := findTemplate(templates, locale, eventType, qualifier)
+	if ok {
+		alert, err := tmpl.render(withLabels(data, locale))
+		if err == nil {
+			return alert
+		}
+		log.Printf("Failed to render %s template for locale %q, using the built-in English template: %v", eventType, locale, err)
+	}
+
+	tmpl, ok = findTemplate(t.builtIn, defaultLocale, eventType, qualifier)
+	if ok {
+		if alert, err := tmpl.render(withLabels(data, defaultLocale)); err == nil {
+			return alert
+		}
+	}
+	return Alert{Title: data.Park, Body: data.Entity}
+}
+
+// findTemplate looks a template up, falling back through less specific locales
+func findTemplate(templates map[string]map[string]compiledTemplate, locale string, eventType string, qualifier string) (compiledTemplate, bool) {
+	for _, candidate := range localeCandidates(locale) {
+		byKey := templates[candidate]
+		if byKey == nil {
+			continue
+		}
+		if qualifier != "" {
+			if tmpl, ok := byKey[eventType+"."+qualifier]; ok {
+				return tmpl, true
+			}
+		}
+		if tmpl, ok := byKey[eventType]; ok {
+			return tmpl, true
+		}
+	}
+	return compiledTemplate{}, false
+}
+
+// withLabels returns data with the status and queue names in a locale
+func withLabels(data NotificationData, locale string) NotificationData {
+	data.OldStatusName = findLabel(locale, data.OldStatus)
+	data.NewStatusName = findLabel(locale, data.NewStatus)
+	data.QueueName = findLabel(locale, data.QueueType)
+	return data
+}
+
+// findLabel looks up the localized name of a status or queue type, falling back through less
+// specific locales, or returns the value itself if no locale names it
+func findLabel(locale string, value string) string {
+	for _, candidate := range localeCandidates(locale) {
+		if label, ok := defaultLabels[candidate][value]; ok {
+			return label
+		}
+	}
+	return value
+}
+
+// render executes the title and body templates
+func (c compiledTemplate) render(data NotificationData) (Alert, error) {
+	var title, body bytes.Buffer
+	if err := c.title.Execute(&title, data); err != nil {
+		return Alert{}, err
+	}
+	if err := c.body.Execute(&body, data); err != nil {
+		return Alert{}, err
+	}
+	return Alert{Title: title.String(), Body: body.String()}, nil
+}
+
+// localeCandidates lists the locales to try for a device's locale, most specific first,
+// ending with the default locale
+func localeCandidates(locale string) []string {
+	var candidates []string
+	for locale = normalizeLocale(locale); locale != ""; {
+		candidates = append(candidates, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return append(candidates, defaultLocale)
+}
+
+// normalizeLocale lower-cases a locale and uses "-" as the separator, so ja_JP and ja-jp both become ja-jp
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"sync"
 	"time"
@@ -10,40 +13,20 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-type ParkType string
-
-const (
-	Disney   ParkType = "disney"
-	Universal ParkType = "universal"
-)
-
-type Park struct {
-	ID         string
-	Name       string
-	Type       ParkType
-	IsSelected bool
-	IsVisible  bool
-}
-
-var parks = []Park{
-	// Disney Parks
-	{ID: "bfc89fd6-314d-44b4-b89e-df1a89cf991e", Name: "Disneyland Resort"},
-	{ID: "e957da41-3552-4cf6-b636-5babc5cbc4e5", Name: "Walt Disney World® Resort"},
-	{ID: "abcfffe7-01f2-4f92-ae61-5093346f5a68", Name: "Hong Kong Disneyland Parks"},
-	{ID: "faff60df-c766-4470-8adb-dee78e813f42", Name: "Tokyo Disney Resort"},
-	{ID: "6e1464ca-1e9b-49c3-8937-c5c6f6675057", Name: "Shanghai Disney Resort"},
-	{ID: "e8d0207f-da8a-4048-bec8-117aa946b2c2", Name: "Disneyland Paris"},
-
-	// Universal Parks
-	{ID: "9fc68f1c-3f5e-4f09-89f2-aab2cf1a0741", Name: "Universal Studios"},
-	{ID: "89db5d43-c434-4097-b71f-f6869f495a22", Name: "Universal Orlando Resort"},
-}
-
 type WebSocketClient struct {
-	url     string
-	apiKey  string
-	conn    *websocket.Conn
-	done    chan struct{}
+	url            string
+	apiKey         string
+	conn           *websocket.Conn
+	connMu         sync.Mutex // guards conn and disconnectedAt, and serializes writes to conn
+	disconnectedAt time.Time
+	done           chan struct{}
+	parkManager    *ParkManager
+	reconciler     *Reconciler
+	hasConnected   bool
+	backoff        Backoff
+	breaker        *CircuitBreaker
+	keepalive      KeepaliveConfig
+	recorder       *FrameRecorder // optional, records every raw frame
 	
 	// Message counters
 	messageCounts struct {
@@ -51,13 +34,43 @@ type WebSocketClient struct {
 		eventCounts  map[string]uint64
 		statusCounts map[EntityStatus]uint64
 	}
+
+	// When messages were last received, overall and per configured park
+	activity struct {
+		sync.RWMutex
+		lastMessage     time.Time
+		parks           map[string]time.Time
+		staleReconnects uint64
+	}
+}
+
+// RetryConfig controls how the client waits between connection attempts
+type RetryConfig struct {
+	InitialDelay     time.Duration
+	MaxDelay         time.Duration
+	BreakerThreshold int           // consecutive failed attempts before the breaker opens
+	BreakerCooldown  time.Duration // how long the breaker stays open
 }
 
+// KeepaliveConfig controls how the client detects a dead connection
+type KeepaliveConfig struct {
+	PingInterval time.Duration // how often to send pings, 0 to disable
+	StaleTimeout time.Duration // reconnect when no message or pong arrives for this long, 0 to disable
+}
+
+// pingWriteTimeout bounds how long sending a ping may block
+const pingWriteTimeout = 10 * time.Second
+
+// stableConnectionAge is how long a connection must stay up before the backoff resets.
+// Connections dropped sooner count as failed attempts, so a server that accepts and
+// immediately closes the socket still backs off.
+const stableConnectionAge = time.Minute
+
 // SubscriptionMessage represents the message sent to subscribe to an entity
 type SubscriptionMessage struct {
 	Event    string `json:"event"`
 	EntityID string `json:"entityId"`
-	EntityTypeFilter string `json:"entityTypeFilter"`
+	EntityTypeFilter string `json:"entityTypeFilter,omitempty"`
 }
 
 // LiveDataMessage represents the full WebSocket message structure
@@ -68,23 +81,30 @@ type LiveDataMessage struct {
 	EntityID   string `json:"entityId"`
 	ParkID     string `json:"parkId"`
 	Data       struct {
-		Queue struct {
-			STANDBY struct {
-				WaitTime *int `json:"waitTime"`
-			} `json:"STANDBY"`
-		} `json:"queue"`
-		Status string `json:"status"`
+		Queue          map[string]QueueData `json:"queue"`
+		Status         string               `json:"status"`
+		OperatingHours []OperatingHour      `json:"operatingHours"`
+		Showtimes      []Showtime           `json:"showtimes"`
+		LastUpdated    string               `json:"lastUpdated"`
 	} `json:"data"`
 }
 
-func NewWebSocketClient(url, apiKey string) *WebSocketClient {
+func NewWebSocketClient(url, apiKey string, parkManager *ParkManager, reconciler *Reconciler, retry RetryConfig, keepalive KeepaliveConfig) *WebSocketClient {
 	client := &WebSocketClient{
-		url:    url,
-		apiKey: apiKey,
-		done:   make(chan struct{}),
+		url:         url,
+		apiKey:      apiKey,
+		done:        make(chan struct{}),
+		parkManager: parkManager,
+		reconciler:  reconciler,
+		backoff:     Backoff{Initial: retry.InitialDelay, Max: retry.MaxDelay},
+		breaker:     &CircuitBreaker{Threshold: retry.BreakerThreshold, Cooldown: retry.BreakerCooldown},
+		keepalive:   keepalive,
 	}
+	// Not connected yet, so the outage clock starts now
+	client.disconnectedAt = time.Now()
 	client.messageCounts.eventCounts = make(map[string]uint64)
 	client.messageCounts.statusCounts = make(map[EntityStatus]uint64)
+	client.activity.parks = make(map[string]time.Time)
 	return client
 }
 
@@ -100,6 +120,39 @@ func (c *WebSocketClient) incrementStatusCounter(status EntityStatus) {
 	c.messageCounts.statusCounts[status]++
 }
 
+// Name identifies the source in logs and metrics
+func (c *WebSocketClient) Name() string {
+	return SourceWebSocket
+}
+
+// Start connects and streams live data until the client is closed. It blocks, so call it in a goroutine.
+func (c *WebSocketClient) Start() {
+	c.Connect()
+}
+
+// SetRecorder records every raw frame received from now on
+func (c *WebSocketClient) SetRecorder(recorder *FrameRecorder) {
+	c.recorder = recorder
+}
+
+// recordActivity notes that a message was received, for the configured park it belongs to if any
+func (c *WebSocketClient) recordActivity(parkID string) {
+	now := time.Now()
+
+	if parkID != "" {
+		if park, ok := c.parkManager.ResolvePark(parkID); ok {
+			parkID = park.ID
+		}
+	}
+
+	c.activity.Lock()
+	defer c.activity.Unlock()
+	c.activity.lastMessage = now
+	if parkID != "" {
+		c.activity.parks[parkID] = now
+	}
+}
+
 func (c *WebSocketClient) Connect() {
 	for {
 		select {
@@ -137,46 +190,164 @@ func (c *WebSocketClient) Connect() {
 						log.Printf("Response Status: %s", resp.Status)
 						log.Printf("Response Headers: %v", resp.Header)
 					}
-					time.Sleep(5 * time.Second)
+					c.breaker.RecordFailure()
+					if !c.waitToReconnect() {
+						return
+					}
 					continue
 				}
 			}
 
+			c.connMu.Lock()
 			c.conn = conn
+			gap := time.Since(c.disconnectedAt)
+			c.connMu.Unlock()
+			connectedAt := time.Now()
 			// Record the reconnection timestamp
 			AddReconnectionTimestamp()
 			log.Printf("[%s] Connected to WebSocket", time.Now().Format("2006-01-02 15:04:05 MST"))
 
-			// Subscribe to all parks
-			for _, park := range parks {
-				if err := c.subscribe(park.ID); err != nil {
-					log.Printf("Failed to subscribe to %s (%s): %v", park.Name, park.ID, err)
-				} else {
-					log.Printf("Subscribed to %s (%s)", park.Name, park.ID)
-				}
+			// Subscribe to all enabled parks
+			for _, park := range c.parkManager.GetEnabledParks() {
+				c.SubscribePark(park)
+			}
+
+			// The first connection follows the REST pre-population; after a reconnect,
+			// catch up on whatever changed while we were down
+			if c.hasConnected {
+				go c.reconciler.GapFill(gap)
+			}
+			c.hasConnected = true
+
+			// A pong answering our ping shows the connection is alive even while the feed is quiet
+			if c.keepalive.StaleTimeout > 0 {
+				conn.SetPongHandler(func(string) error {
+					return conn.SetReadDeadline(time.Now().Add(c.keepalive.StaleTimeout))
+				})
 			}
 
-			// Start reading messages
+			stopPings := make(chan struct{})
+			go c.sendPings(conn, stopPings)
+
+			// Start reading messages. The read deadline is pushed back on every message and pong,
+			// so a dead connection (including a half-open TCP connection) times out the read.
 			for {
-				_, message, err := c.conn.ReadMessage()
+				if c.keepalive.StaleTimeout > 0 {
+					conn.SetReadDeadline(time.Now().Add(c.keepalive.StaleTimeout))
+				}
+				_, message, err := conn.ReadMessage()
 				if err != nil {
-					log.Printf("Read error: %v", err)
+					var netErr net.Error
+					if errors.As(err, &netErr) && netErr.Timeout() {
+						log.Printf("No messages or pongs for %v, forcing reconnect", c.keepalive.StaleTimeout)
+						c.activity.Lock()
+						c.activity.staleReconnects++
+						c.activity.Unlock()
+					} else {
+						log.Printf("Read error: %v", err)
+					}
 					break
 				}
+				if c.recorder != nil {
+					c.recorder.Record(message)
+				}
 				c.handleMessage(message)
 			}
+			close(stopPings)
+
+			c.connMu.Lock()
+			c.conn = nil
+			c.disconnectedAt = time.Now()
+			c.connMu.Unlock()
+			conn.Close()
+
+			if time.Since(connectedAt) >= stableConnectionAge {
+				c.breaker.RecordSuccess()
+				c.backoff.Reset()
+			} else {
+				c.breaker.RecordFailure()
+			}
+			if !c.waitToReconnect() {
+				return
+			}
+		}
+	}
+}
 
-			c.conn.Close()
-			time.Sleep(5 * time.Second)
+// sendPings pings the server on every PingInterval until stop is closed, so dead
+// connections surface as write errors and idle connections aren't dropped by proxies
+func (c *WebSocketClient) sendPings(conn *websocket.Conn, stop chan struct{}) {
+	if c.keepalive.PingInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.keepalive.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// WriteControl is safe to call concurrently with the other connection methods
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteTimeout)); err != nil {
+				log.Printf("Failed to send ping: %v", err)
+				return
+			}
 		}
 	}
 }
 
-func (c *WebSocketClient) subscribe(entityID string) error {
+// waitToReconnect sleeps for the next backoff delay, or until the circuit breaker's
+// cooldown ends if it is open. It returns false if the client was closed while waiting.
+func (c *WebSocketClient) waitToReconnect() bool {
+	delay := c.backoff.Next()
+	if cooldown := c.breaker.RemainingCooldown(); cooldown > delay {
+		log.Printf("Circuit breaker open, waiting %v before reconnecting", cooldown.Round(time.Second))
+		delay = cooldown
+	} else {
+		log.Printf("Reconnecting in %v", delay.Round(time.Millisecond))
+	}
+
+	select {
+	case <-c.done:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// SubscribePark starts receiving live data for a park. If the socket is not
+// connected the park is picked up when the next connection subscribes to all enabled parks.
+func (c *WebSocketClient) SubscribePark(park Park) {
+	// The feed filters by a single entity type; for several, receive everything and filter in handleMessage
+	entityTypeFilter := ""
+	if entityTypes := park.TrackedEntityTypes(); len(entityTypes) == 1 {
+		entityTypeFilter = entityTypes[0]
+	}
+
+	if err := c.send("subscribe", park.ID, entityTypeFilter); err != nil {
+		log.Printf("Failed to subscribe to %s (%s): %v", park.Name, park.ID, err)
+	} else {
+		log.Printf("Subscribed to %s (%s)", park.Name, park.ID)
+	}
+}
+
+// UnsubscribePark stops receiving live data for a park
+func (c *WebSocketClient) UnsubscribePark(park Park) {
+	if err := c.send("unsubscribe", park.ID, ""); err != nil {
+		log.Printf("Failed to unsubscribe from %s (%s): %v", park.Name, park.ID, err)
+	} else {
+		log.Printf("Unsubscribed from %s (%s)", park.Name, park.ID)
+	}
+}
+
+// send writes a subscription message for an entity on the current connection
+func (c *WebSocketClient) send(event string, entityID string, entityTypeFilter string) error {
 	msg := SubscriptionMessage{
-		Event:    "subscribe",
+		Event:    event,
 		EntityID: entityID,
-		EntityTypeFilter: "ATTRACTION",
+		EntityTypeFilter: entityTypeFilter,
 	}
 
 	data, err := json.Marshal(msg)
@@ -184,13 +355,18 @@ func (c *WebSocketClient) subscribe(entityID string) error {
 		return err
 	}
 
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn == nil {
+		return fmt.Errorf("not connected")
+	}
+
 	log.Printf("Sending subscription message: %s", string(data))
 	return c.conn.WriteMessage(websocket.TextMessage, data)
 }
 
 func (c *WebSocketClient) handleMessage(message []byte) {
 	timestamp := time.Now().Format("2006-01-02 15:04:05 MST")
-	// log.Printf("[%s] Raw message: %s", timestamp, string(message))
 
 	var msg LiveDataMessage
 	if err := json.Unmarshal(message, &msg); err != nil {
@@ -204,28 +380,35 @@ func (c *WebSocketClient) handleMessage(message []byte) {
 	}
 
 	c.incrementCounter(msg.Event)
+	c.recordActivity(msg.ParkID)
 
 	if msg.Event == "heartbeat" {
 		return
 	}
 
 	if msg.Event == "livedata" {
+		// Skip entity types the park doesn't track
+		if !c.parkManager.TracksEntityType(msg.ParkID, msg.EntityType) {
+			return
+		}
+
 		// Increment status counter
 		c.incrementStatusCounter(EntityStatus(msg.Data.Status))
 		
 		// Create entity from message
-		waitTime := 0
-		if msg.Data.Queue.STANDBY.WaitTime != nil {
-			waitTime = *msg.Data.Queue.STANDBY.WaitTime
-		}
+		waitTime := standbyWait(msg.Data.Queue)
 
 		entity := Entity{
-			EntityID:   msg.EntityID,
-			Name:       msg.Name,
-			EntityType: msg.EntityType,
-			ParkID:     msg.ParkID,
-			WaitTime:   waitTime,
-			Status:     EntityStatus(msg.Data.Status),
+			EntityID:       msg.EntityID,
+			Name:           msg.Name,
+			EntityType:     msg.EntityType,
+			ParkID:         msg.ParkID,
+			WaitTime:       waitTime,
+			Status:         EntityStatus(msg.Data.Status),
+			OperatingHours: msg.Data.OperatingHours,
+			Queues:         msg.Data.Queue,
+			Showtimes:      msg.Data.Showtimes,
+			LastUpdated:    parseLastUpdated(msg.EntityID, msg.Data.LastUpdated),
 		}
 
 		// Queue the entity for processing
@@ -238,13 +421,68 @@ func (c *WebSocketClient) handleMessage(message []byte) {
 	}
 }
 
+// IsConnected reports whether the client currently has an open connection
+func (c *WebSocketClient) IsConnected() bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.conn != nil
+}
+
+// DisconnectedSince returns when the client last lost (or never had) a connection.
+// It is only meaningful while IsConnected is false.
+func (c *WebSocketClient) DisconnectedSince() time.Time {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.disconnectedAt
+}
+
 func (c *WebSocketClient) Close() {
 	close(c.done)
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
 	if c.conn != nil {
 		c.conn.Close()
 	}
 }
 
+// GetBreakerStats returns the reconnection circuit breaker's state for the metrics endpoint
+func (c *WebSocketClient) GetBreakerStats() map[string]interface{} {
+	return c.breaker.GetStats()
+}
+
+// GetActivityStats returns how long ago messages were last received, overall and for
+// each enabled park, for the metrics endpoint. Parks that never sent a message are null.
+func (c *WebSocketClient) GetActivityStats() map[string]interface{} {
+	parks := c.parkManager.GetEnabledParks()
+
+	c.activity.RLock()
+	defer c.activity.RUnlock()
+
+	parkStats := make(map[string]interface{})
+	for _, park := range parks {
+		var seconds interface{}
+		if last, ok := c.activity.parks[park.ID]; ok {
+			seconds = time.Since(last).Seconds()
+		}
+		parkStats[park.ID] = map[string]interface{}{
+			"name":                       park.Name,
+			"seconds_since_last_message": seconds,
+		}
+	}
+
+	var seconds interface{}
+	if !c.activity.lastMessage.IsZero() {
+		seconds = time.Since(c.activity.lastMessage).Seconds()
+	}
+
+	return map[string]interface{}{
+		"seconds_since_last_message": seconds,
+		"stale_reconnects":           c.activity.staleReconnects,
+		"stale_timeout_seconds":      c.keepalive.StaleTimeout.Seconds(),
+		"parks":                      parkStats,
+	}
+}
+
 func (c *WebSocketClient) GetEventStats() map[string]uint64 {
 	c.messageCounts.RLock()
 	defer c.messageCounts.RUnlock()